@@ -0,0 +1,236 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+const (
+	// BloggerKey is the key for published Blogger posts in a ZipCollections
+	// map when the export does not give the title of the blog.
+	BloggerKey = "blogger"
+
+	bloggerKindScheme  = "http://schemas.google.com/g/2005#kind"
+	bloggerKindPost    = "http://schemas.google.com/blogger/2008/kind#post"
+	bloggerLabelScheme = "http://www.blogger.com/atom/ns#"
+)
+
+// bloggerEntry is a single entry from a Blogger Atom export, which holds
+// the comments, settings and template of the blog as well as its posts.
+type bloggerEntry struct {
+	Title      string            `xml:"title"`
+	Content    string            `xml:"content"`
+	Published  string            `xml:"published"`
+	Updated    string            `xml:"updated"`
+	Categories []bloggerCategory `xml:"category"`
+	Links      []bloggerLink     `xml:"link"`
+	Draft      string            `xml:"control>draft"`
+}
+
+type bloggerCategory struct {
+	Scheme string `xml:"scheme,attr"`
+	Term   string `xml:"term,attr"`
+}
+
+type bloggerLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// FromBlogger reads a Blogger Atom export, as found in Google Takeout, from r
+// and returns the posts it contains and an error if any. Comments, settings,
+// templates and pages are left out.
+//
+// Published posts are keyed by the title of the blog and given the slug of
+// their old URL, so that my-post is the slug of
+// https://example.blogspot.com/2019/05/my-post.html. Drafts are included
+// under DraftsKey. Labels are appended to the posts as hashtags. Entries
+// that fail to be converted are handled according to the ErrorPolicy, as is
+// an export cut short, whose posts before the failing entry are kept.
+func FromBlogger(r io.Reader, opts ...Option) (ZipCollections, error) {
+	return fromBlogger(r, newOptions(opts))
+}
+
+// FromBloggerContext works as FromBlogger, checking ctx between entries and
+// while converting them. Once ctx is done the posts read until then are
+// returned along with ctx.Err().
+func FromBloggerContext(ctx context.Context, r io.Reader, opts ...Option) (ZipCollections, error) {
+	return fromBlogger(r, contextOptions(ctx, opts))
+}
+
+func fromBlogger(r io.Reader, o *options) (ZipCollections, error) {
+	out := ZipCollections{DraftsKey: []*writeas.PostParams{}}
+	postErrors, err := fromBloggerFeed(r, o, out)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return out, err
+}
+
+// fromBloggerFeed adds the posts of the export read from r to out. Errors
+// converting single entries, or reading the export past the last complete
+// one, are gathered into postErrors as the error policy allows, while err is
+// set if the import was aborted.
+func fromBloggerFeed(r io.Reader, o *options, out ZipCollections) (postErrors, err error) {
+	key := BloggerKey
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	for i := 1; ; {
+		if err := o.ctx.Err(); err != nil {
+			return postErrors, err
+		}
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			// the rest of the export cannot be read, the posts before it are
+			// kept as the error policy allows
+			if err := o.fileFailed(&postErrors, err); err != nil {
+				return nil, err
+			}
+			return postErrors, nil
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch se.Name.Local {
+		case "title":
+			// only the feed title is seen here, entries are decoded whole
+			var title string
+			if err := d.DecodeElement(&title, &se); err != nil {
+				return nil, err
+			}
+			if title = strings.TrimSpace(title); title != "" {
+				key = title
+			}
+		case "entry":
+			entry := bloggerEntry{}
+			if err := d.DecodeElement(&entry, &se); err != nil {
+				id := strconv.Itoa(i)
+				o.recordPost("", id, nil, err)
+				if err := o.postFailed(&postErrors, "", id, err); err != nil {
+					return nil, err
+				}
+				return postErrors, nil
+			}
+			if entry.kind() != bloggerKindPost {
+				continue
+			}
+			id := entry.slug()
+			if id == "" {
+				id = strconv.Itoa(i)
+			}
+			i++
+			p, err := entry.post(o.ctx)
+			if canceled(o.ctx, err) {
+				return postErrors, err
+			}
+			if err == nil && p == nil {
+				err = ErrEmptyFile
+			}
+			o.recordPost("", id, p, err)
+			if skipped(err) {
+				continue
+			} else if err != nil {
+				if err := o.postFailed(&postErrors, "", id, err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if strings.EqualFold(strings.TrimSpace(entry.Draft), "yes") {
+				out[DraftsKey] = append(out[DraftsKey], p)
+				continue
+			}
+			p.Collection = o.collectionKey(key)
+			out[p.Collection] = append(out[p.Collection], p)
+		}
+	}
+	return postErrors, nil
+}
+
+// kind returns the type of the entry, such as a post or comment.
+func (e bloggerEntry) kind() string {
+	for _, c := range e.Categories {
+		if c.Scheme == bloggerKindScheme {
+			return c.Term
+		}
+	}
+	return ""
+}
+
+// post converts the entry into a post, or returns nil if it has no content.
+func (e bloggerEntry) post(ctx context.Context) (*writeas.PostParams, error) {
+	body, err := htmlStringToMarkdown(ctx, e.Content)
+	if err != nil {
+		return nil, err
+	}
+	var labels []string
+	for _, c := range e.Categories {
+		if c.Scheme == bloggerLabelScheme {
+			labels = append(labels, c.Term)
+		}
+	}
+	if ht := hashtags(labels); ht != "" {
+		body = strings.TrimSpace(body + "\n\n" + ht)
+	}
+	if body == "" {
+		return nil, nil
+	}
+
+	p := &writeas.PostParams{
+		Title:   strings.TrimSpace(e.Title),
+		Slug:    e.slug(),
+		Content: body,
+		Created: bloggerTime(e.Published),
+		Updated: bloggerTime(e.Updated),
+	}
+	return p, nil
+}
+
+// slug returns the slug from the URL the post was published at, without the
+// date and .html extension Blogger adds to it.
+func (e bloggerEntry) slug() string {
+	for _, l := range e.Links {
+		if l.Rel != "alternate" {
+			continue
+		}
+		u, err := url.Parse(l.Href)
+		if err != nil {
+			return ""
+		}
+		base := path.Base(u.Path)
+		if base == "/" || base == "." {
+			return ""
+		}
+		return strings.TrimSuffix(base, path.Ext(base))
+	}
+	return ""
+}
+
+func bloggerTime(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
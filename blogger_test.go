@@ -0,0 +1,131 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const bloggerFeed = `<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns='http://www.w3.org/2005/Atom' xmlns:app='http://purl.org/atom/app#'>
+	<id>tag:blogger.com,1999:blog-1</id>
+	<title type='text'>Travel Notes</title>
+	<entry>
+		<id>tag:blogger.com,1999:blog-1.layout</id>
+		<category scheme='http://schemas.google.com/g/2005#kind' term='http://schemas.google.com/blogger/2008/kind#template'/>
+		<title type='text'>Template: Travel Notes</title>
+		<content type='html'>&lt;html&gt;&lt;/html&gt;</content>
+	</entry>
+	<entry>
+		<id>tag:blogger.com,1999:blog-1.settings.BLOG_NAME</id>
+		<category scheme='http://schemas.google.com/g/2005#kind' term='http://schemas.google.com/blogger/2008/kind#settings'/>
+		<content type='text'>Travel Notes</content>
+	</entry>
+	<entry>
+		<id>tag:blogger.com,1999:blog-1.post-10</id>
+		<published>2019-05-04T10:00:00.000-07:00</published>
+		<updated>2019-05-05T10:00:00.000-07:00</updated>
+		<category scheme='http://schemas.google.com/g/2005#kind' term='http://schemas.google.com/blogger/2008/kind#post'/>
+		<category scheme='http://www.blogger.com/atom/ns#' term='road trips'/>
+		<title type='text'>Leaving Home</title>
+		<content type='html'>&lt;p&gt;We left at &lt;b&gt;dawn&lt;/b&gt;.&lt;/p&gt;</content>
+		<link rel='replies' type='text/html' href='https://travel.blogspot.com/2019/05/leaving-home.html#comment-form'/>
+		<link rel='alternate' type='text/html' href='https://travel.blogspot.com/2019/05/leaving-home.html' title='Leaving Home'/>
+	</entry>
+	<entry>
+		<id>tag:blogger.com,1999:blog-1.post-11</id>
+		<published>2019-06-01T10:00:00.000-07:00</published>
+		<category scheme='http://schemas.google.com/g/2005#kind' term='http://schemas.google.com/blogger/2008/kind#post'/>
+		<title type='text'>Unfinished</title>
+		<content type='html'>&lt;p&gt;Some day.&lt;/p&gt;</content>
+		<app:control><app:draft>yes</app:draft></app:control>
+	</entry>
+	<entry>
+		<id>tag:blogger.com,1999:blog-1.post-12</id>
+		<category scheme='http://schemas.google.com/g/2005#kind' term='http://schemas.google.com/blogger/2008/kind#comment'/>
+		<content type='html'>Nice post!</content>
+	</entry>
+</feed>`
+
+func TestFromBlogger(t *testing.T) {
+	colls, err := FromBlogger(strings.NewReader(bloggerFeed))
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	posts := colls["Travel Notes"]
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts but expected 1", len(posts))
+	}
+	p := posts[0]
+	if p.Title != "Leaving Home" {
+		t.Fatalf("got title %q but expected %q", p.Title, "Leaving Home")
+	}
+	if p.Slug != "leaving-home" {
+		t.Fatalf("got slug %q but expected %q", p.Slug, "leaving-home")
+	}
+	expected := "We left at **dawn**.\n\n#RoadTrips"
+	if p.Content != expected {
+		t.Fatalf("got content %q but expected %q", p.Content, expected)
+	}
+	if p.Collection != "Travel Notes" {
+		t.Fatalf("got collection %q but expected %q", p.Collection, "Travel Notes")
+	}
+	created := time.Date(2019, 5, 4, 17, 0, 0, 0, time.UTC)
+	if p.Created == nil || !p.Created.Equal(created) {
+		t.Fatalf("got created %v but expected %v", p.Created, created)
+	}
+
+	if len(colls[DraftsKey]) != 1 {
+		t.Fatalf("got %d drafts but expected 1", len(colls[DraftsKey]))
+	}
+	if d := colls[DraftsKey][0]; d.Slug != "" || d.Collection != "" {
+		t.Fatalf("got draft slug %q and collection %q but expected neither", d.Slug, d.Collection)
+	}
+}
+
+func TestFromBloggerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	colls, err := FromBloggerContext(ctx, strings.NewReader(bloggerFeed))
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls["Travel Notes"]) != 0 {
+		t.Fatalf("got %d posts but expected 0", len(colls["Travel Notes"]))
+	}
+}
+
+func TestFromBloggerErrors(t *testing.T) {
+	truncated := bloggerFeed[:strings.Index(bloggerFeed, "Some day.")]
+	r := &Report{}
+	colls, err := FromBlogger(strings.NewReader(truncated), WithReport(r))
+	merr, ok := err.(*multierror.Error)
+	if !ok || len(merr.Errors) != 1 {
+		t.Fatalf("got error %v but expected one collected error", err)
+	}
+	if len(colls["Travel Notes"]) != 1 {
+		t.Fatalf("got %d posts but expected the one before the failing entry", len(colls["Travel Notes"]))
+	}
+	if len(r.Files) != 2 || r.Files[0].Path != "leaving-home" || r.Files[0].Outcome != Imported || r.Files[1].Outcome != Failed {
+		t.Fatalf("got report %+v but expected the imported and failed entries", r.Files)
+	}
+
+	colls, err = FromBlogger(strings.NewReader(truncated), WithErrorPolicy(FailFast))
+	if err == nil || colls != nil {
+		t.Fatalf("got %v and error %v but expected to fail", colls, err)
+	}
+
+	colls, err = FromBlogger(strings.NewReader(truncated), WithErrorPolicy(SkipSilently))
+	if err != nil || len(colls["Travel Notes"]) != 1 {
+		t.Fatalf("got %v and error %v but expected the complete post alone", colls, err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+// dayOneMomentRx matches the Markdown references Day One entries make to
+// their photos and other media, which are not imported.
+var dayOneMomentRx = regexp.MustCompile(`!\[[^\]]*\]\(dayone-moment:[^)]*\)[ \t]*\n?`)
+
+// dayOneJournal is a journal from a Day One JSON export.
+type dayOneJournal struct {
+	Entries []dayOneEntry `json:"entries"`
+}
+
+type dayOneEntry struct {
+	Text         string     `json:"text"`
+	CreationDate *time.Time `json:"creationDate"`
+	ModifiedDate *time.Time `json:"modifiedDate"`
+	TimeZone     string     `json:"timeZone"`
+	Tags         []string   `json:"tags"`
+}
+
+// FromDayOne reads a Day One JSON export archive and returns the entries it
+// contains keyed by the name of their journal, and an error if any. Each
+// journal is a JSON file at the top of the archive, such as Journal.json.
+//
+// Entries are titled by a leading Markdown heading and have their tags
+// appended as hashtags. Photos and other media are left out. Dates are given
+// in the time zone the entry was written in. Journals that fail to be read
+// are handled according to the ErrorPolicy.
+func FromDayOne(archive string, opts ...Option) (ZipCollections, error) {
+	return fromDayOne(archive, newOptions(opts))
+}
+
+// FromDayOneContext works as FromDayOne, checking ctx between entries. Once
+// ctx is done the entries read until then are returned along with ctx.Err().
+func FromDayOneContext(ctx context.Context, archive string, opts ...Option) (ZipCollections, error) {
+	return fromDayOne(archive, contextOptions(ctx, opts))
+}
+
+func fromDayOne(archive string, o *options) (ZipCollections, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var postErrors error
+	out := ZipCollections{}
+	for _, f := range r.File {
+		if path.Dir(f.Name) != "." || strings.ToLower(path.Ext(f.Name)) != ".json" {
+			continue
+		}
+		err := fromDayOneJournal(f, o, out)
+		if canceled(o.ctx, err) {
+			return out, err
+		}
+		o.record(f.Name, "", nil, err)
+		if err != nil && !skipped(err) {
+			if err := o.fileFailed(&postErrors, fileError(f.Name, archive, err)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, postErrors
+}
+
+// fromDayOneJournal adds the entries of the journal in f to out.
+func fromDayOneJournal(f *zip.File, o *options, out ZipCollections) error {
+	b, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return ErrEmptyFile
+	}
+	journal := dayOneJournal{}
+	if err := json.Unmarshal(b, &journal); err != nil {
+		return err
+	}
+
+	name := o.collectionKey(strings.TrimSuffix(f.Name, path.Ext(f.Name)))
+	for _, e := range journal.Entries {
+		if err := o.ctx.Err(); err != nil {
+			return err
+		}
+		p := e.post()
+		if p == nil {
+			continue
+		}
+		p.Collection = name
+		out[name] = append(out[name], p)
+	}
+	return nil
+}
+
+// post converts the entry into a post, or returns nil if it has no content.
+func (e dayOneEntry) post() *writeas.PostParams {
+	text := dayOneMomentRx.ReplaceAllString(normalizeText(e.Text), "")
+	title, body := extractTitle(strings.TrimSpace(text))
+	if ht := hashtags(e.Tags); ht != "" {
+		body = strings.TrimSpace(body + "\n\n" + ht)
+	}
+	if title == "" && body == "" {
+		return nil
+	}
+
+	p := &writeas.PostParams{
+		Title:   title,
+		Content: body,
+		Created: e.localTime(e.CreationDate),
+		Updated: e.localTime(e.ModifiedDate),
+	}
+	return p
+}
+
+// localTime returns t in the time zone of the entry, or as is if the time
+// zone is not known.
+func (e dayOneEntry) localTime(t *time.Time) *time.Time {
+	if t == nil || e.TimeZone == "" {
+		return t
+	}
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return t
+	}
+	local := t.In(loc)
+	return &local
+}
@@ -0,0 +1,139 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const dayOneJSON = `{
+	"metadata": {"version": "1.0"},
+	"entries": [
+		{
+			"uuid": "A1",
+			"creationDate": "2020-03-01T20:15:00Z",
+			"modifiedDate": "2020-03-02T08:00:00Z",
+			"timeZone": "Europe/Berlin",
+			"tags": ["travel", "spring break"],
+			"text": "# Arrived\n\n![](dayone-moment://0F2E3D)\nThe train was late.\n"
+		},
+		{
+			"uuid": "A2",
+			"creationDate": "2020-03-03T07:00:00Z",
+			"text": "Just a thought, no heading."
+		},
+		{
+			"uuid": "A3",
+			"creationDate": "2020-03-04T07:00:00Z",
+			"text": "![](dayone-moment://1A2B3C)"
+		}
+	]
+}`
+
+func TestFromDayOne(t *testing.T) {
+	archive := getTestZip(t, fileList{
+		{"Journal.json", dayOneJSON},
+		{"Dreams.json", `{"entries": [{"text": "Flying again."}]}`},
+		{"photos/0F2E3D.jpeg", "not really a photo"},
+	})
+	defer os.Remove(archive)
+
+	colls, err := FromDayOne(archive)
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	if len(colls["Journal"]) != 2 {
+		t.Fatalf("got %d posts in Journal but expected 2", len(colls["Journal"]))
+	}
+	if len(colls["Dreams"]) != 1 {
+		t.Fatalf("got %d posts in Dreams but expected 1", len(colls["Dreams"]))
+	}
+
+	p := colls["Journal"][0]
+	if p.Title != "Arrived" {
+		t.Fatalf("got title %q but expected %q", p.Title, "Arrived")
+	}
+	expected := "The train was late.\n\n#Travel #SpringBreak"
+	if p.Content != expected {
+		t.Fatalf("got content %q but expected %q", p.Content, expected)
+	}
+	if p.Collection != "Journal" {
+		t.Fatalf("got collection %q but expected %q", p.Collection, "Journal")
+	}
+	created := time.Date(2020, 3, 1, 20, 15, 0, 0, time.UTC)
+	if p.Created == nil || !p.Created.Equal(created) {
+		t.Fatalf("got created %v but expected %v", p.Created, created)
+	}
+	if _, err := time.LoadLocation("Europe/Berlin"); err == nil && p.Created.Location().String() != "Europe/Berlin" {
+		t.Fatalf("got time zone %s but expected Europe/Berlin", p.Created.Location())
+	}
+
+	p = colls["Journal"][1]
+	if p.Title != "" || p.Content != "Just a thought, no heading." {
+		t.Fatalf("got title %q and content %q but expected only content", p.Title, p.Content)
+	}
+}
+
+func TestFromDayOneInvalid(t *testing.T) {
+	archive := getTestZip(t, fileList{
+		{"Journal.json", `{"entries": [`},
+		{"Empty.json", ""},
+		{"Travel.json", dayOneJSON},
+	})
+	defer os.Remove(archive)
+
+	r := &Report{}
+	colls, err := FromDayOne(archive, WithReport(r))
+	merr, ok := err.(*multierror.Error)
+	if !ok || len(merr.Errors) != 1 {
+		t.Fatalf("got error %v but expected one collected error", err)
+	}
+	fe, ok := merr.Errors[0].(*FileError)
+	if !ok {
+		t.Fatalf("got error %v but expected a *FileError", merr.Errors[0])
+	}
+	if fe.Path != "Journal.json" || fe.Archive != archive {
+		t.Fatalf("got error for %s in %s but expected Journal.json in %s", fe.Path, fe.Archive, archive)
+	}
+	if len(colls["Travel"]) == 0 {
+		t.Fatal("expected the valid journal to be imported")
+	}
+	outcomes := []Outcome{Failed, SkippedEmpty, Imported}
+	if len(r.Files) != len(outcomes) {
+		t.Fatalf("got %d reported files but expected %d", len(r.Files), len(outcomes))
+	}
+	for i, outcome := range outcomes {
+		if r.Files[i].Outcome != outcome {
+			t.Fatalf("got outcome %v for %s but expected %v", r.Files[i].Outcome, r.Files[i].Path, outcome)
+		}
+	}
+
+	colls, err = FromDayOne(archive, WithErrorPolicy(FailFast))
+	if _, ok := err.(*FileError); !ok || colls != nil {
+		t.Fatalf("got %v and error %v but expected a *FileError alone", colls, err)
+	}
+}
+
+func TestFromDayOneContext(t *testing.T) {
+	archive := getTestZip(t, fileList{{"Journal.json", dayOneJSON}})
+	defer os.Remove(archive)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	colls, err := FromDayOneContext(ctx, archive)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls["Journal"]) != 0 {
+		t.Fatalf("got %d posts but expected 0", len(colls["Journal"]))
+	}
+}
@@ -10,9 +10,39 @@ writefreely posts from various files. https://github.com/writeas/writefreely
 
 Status
 
-Current support is for files, directories and zip archives.
-Support is planned for exported data from Medium, Ghost, Wordpress and
-writefreely exports in zip or json.
+Current support is for files, directory trees, zip and tar archives, the
+latter optionally compressed with gzip or zstd, and any fs.FS through
+FromFS. YAML, TOML and JSON front matter at the top of a file is parsed into
+the post's fields.
+Titles are taken from a leading Markdown heading, and WithTitleStrategy can
+find them in plain text files too, from their first line, a Setext heading or
+the file name. Files in UTF-16, windows-1252 or the common ISO-8859
+encodings, as often saved on Windows, are detected and transcoded to UTF-8,
+and stray invalid bytes in UTF-8 files are replaced.
+Files are parsed by the Parser registered for their extension or MIME type,
+plain text, Markdown and HTML being built in, and RegisterParser adds
+converters for other formats. HTML files are converted to Markdown, taking
+the title, language, direction and date from the document.
+Medium export archives are supported with FromMedium, Ghost JSON exports
+with FromGhost, WordPress WXR exports with FromWordPress, Evernote ENEX
+exports with FromENEX, Day One JSON exports with FromDayOne, Blogger Atom
+exports with FromBlogger, Tumblr backups with FromTumblr, Substack exports
+with FromSubstack and writefreely JSON exports with FromWriteFreelyJSON.
+Hugo and Jekyll site directories are read with FromStaticSite, which follows
+their conventions for sections, drafts and post file names.
+
+Large sources can be read one post at a time with WalkFS, WalkZip and WalkTar
+rather than collecting every post in memory first.
+
+Imports can be cancelled or time limited through the ...Context variants of
+the importers, such as FromZipContext, which stop between files and during
+HTML conversions once their context is done. The posts read until then are
+returned along with ctx.Err().
+
+Passing WithReport to an importer records what became of every file it read,
+whether imported, skipped as empty or binary, or failed, and why. Files that
+fail do not stop an import by default, WithErrorPolicy can make it stop at
+the first one or leave them out silently.
 
 About Posts
 
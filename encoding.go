@@ -0,0 +1,244 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	textunicode "golang.org/x/text/encoding/unicode"
+)
+
+// Names of the character encodings files are detected to be in, as reported
+// in FileReport.Encoding. Files in any of them are transcoded to UTF-8.
+const (
+	EncodingUTF8        = "UTF-8"
+	EncodingUTF16LE     = "UTF-16LE"
+	EncodingUTF16BE     = "UTF-16BE"
+	EncodingWindows1252 = "windows-1252"
+	EncodingISO88591    = "ISO-8859-1"
+	EncodingISO88592    = "ISO-8859-2"
+	EncodingISO88595    = "ISO-8859-5"
+	EncodingISO88597    = "ISO-8859-7"
+	EncodingISO885915   = "ISO-8859-15"
+)
+
+// latinCharmaps and otherCharmaps are the ISO-8859 encodings told apart by
+// detectEncoding, for Latin scripts and others. The first is preferred when
+// they fit the content as well.
+var (
+	latinCharmaps = []string{EncodingISO88591, EncodingISO885915, EncodingISO88592}
+	otherCharmaps = []string{EncodingISO88595, EncodingISO88597}
+)
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// sniffLen is the number of bytes looked at to detect UTF-16 without a byte
+// order mark, the same as http.DetectContentType considers.
+const sniffLen = 512
+
+// decodeText detects the character encoding of b and returns its content as
+// UTF-8 along with the name of the encoding. ErrInvalidContentType is
+// returned if the content is not text, holding NUL bytes once decoded.
+//
+// UTF-16 is recognized by its byte order mark or, without one, by the zero
+// bytes of mostly ASCII text. Text that is mostly valid UTF-8 is read as
+// such, with the invalid bytes replaced by U+FFFD. Other content is taken to
+// be in windows-1252, as saved by Windows Notepad, or in the ISO-8859
+// encoding its letters fit best if it has none of the characters where the
+// two differ.
+func decodeText(b []byte) (string, string, error) {
+	enc := detectEncoding(b)
+	if enc == EncodingUTF16LE || enc == EncodingUTF16BE {
+		// UTF-16 looks binary until decoded
+		decoded, err := textDecoder(enc).Bytes(b)
+		if err != nil {
+			return "", enc, err
+		}
+		b = decoded
+	}
+	// the parser was chosen for the file already, by its extension or the
+	// MIME type sniffed from it, so only content no text has is refused
+	if bytes.IndexByte(b, 0) != -1 {
+		return "", "", ErrInvalidContentType
+	}
+	switch enc {
+	case EncodingUTF8:
+		if !utf8.Valid(b) {
+			b = bytes.ToValidUTF8(b, []byte("\uFFFD"))
+		}
+	case EncodingUTF16LE, EncodingUTF16BE:
+		// decoded above
+	default:
+		decoded, err := textDecoder(enc).Bytes(b)
+		if err != nil {
+			return "", enc, err
+		}
+		b = decoded
+	}
+	return string(b), enc, nil
+}
+
+// detectEncoding returns the name of the character encoding b is in.
+func detectEncoding(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, utf8BOM):
+		return EncodingUTF8
+	case bytes.HasPrefix(b, utf16LEBOM):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(b, utf16BEBOM):
+		return EncodingUTF16BE
+	}
+	if enc := sniffUTF16(b); enc != "" {
+		return enc
+	}
+	if mostlyUTF8(b) {
+		return EncodingUTF8
+	}
+	for _, c := range b {
+		// C1 control characters in ISO-8859 are printable in windows-1252
+		if c >= 0x80 && c <= 0x9f {
+			return EncodingWindows1252
+		}
+	}
+	return detectISO8859(b)
+}
+
+// mostlyUTF8 reports whether b is valid UTF-8 or has at least as many
+// multibyte characters as invalid bytes, as in UTF-8 text with the odd byte
+// from another encoding pasted in.
+func mostlyUTF8(b []byte) bool {
+	var multibyte, invalid int
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			invalid++
+		case size > 1:
+			multibyte++
+		}
+		b = b[size:]
+	}
+	return invalid == 0 || multibyte >= invalid
+}
+
+// detectISO8859 returns the ISO-8859 encoding b is most likely in. Text
+// where most bytes above ASCII come in runs, making up whole words, is taken
+// to be in a non-Latin script. The encoding giving the most letters for
+// those bytes is then picked from those for the script, not counting the
+// Cyrillic letters outside of the Russian alphabet that Greek text would
+// read as.
+func detectISO8859(b []byte) string {
+	var high, inRuns, run int
+	for i := 0; i <= len(b); i++ {
+		if i < len(b) && b[i] >= 0xa0 {
+			high++
+			run++
+			continue
+		}
+		if run >= 3 {
+			inRuns += run
+		}
+		run = 0
+	}
+
+	candidates := latinCharmaps
+	if inRuns*2 > high {
+		candidates = otherCharmaps
+	}
+	best, bestLetters := candidates[0], -1
+	for _, enc := range candidates {
+		cm := isoCharmap(enc)
+		letters := 0
+		for _, c := range b {
+			if c >= 0xa0 && isoLetter(cm.DecodeByte(c)) {
+				letters++
+			}
+		}
+		if letters > bestLetters {
+			best, bestLetters = enc, letters
+		}
+	}
+	return best
+}
+
+// sniffUTF16 detects UTF-16 without a byte order mark from the start of b,
+// where nearly every other byte is zero in mostly ASCII text. It returns an
+// empty string if b does not look like UTF-16.
+func sniffUTF16(b []byte) string {
+	if len(b) > sniffLen {
+		b = b[:sniffLen]
+	}
+	pairs := len(b) / 2
+	if pairs < 2 {
+		return ""
+	}
+	var evenZero, oddZero int
+	for i := 0; i+1 < len(b); i += 2 {
+		switch {
+		case b[i] != 0 && b[i+1] == 0:
+			oddZero++
+		case b[i] == 0 && b[i+1] != 0:
+			evenZero++
+		}
+	}
+	switch {
+	case oddZero*10 >= pairs*9:
+		return EncodingUTF16LE
+	case evenZero*10 >= pairs*9:
+		return EncodingUTF16BE
+	}
+	return ""
+}
+
+func isoLetter(r rune) bool {
+	if r >= 0x400 && r < 0x460 && (r < 0x410 || r >= 0x450) && r != 'Ё' && r != 'ё' {
+		return false
+	}
+	return unicode.IsLetter(r)
+}
+
+// textDecoder returns the decoder for the named encoding.
+func textDecoder(enc string) *encoding.Decoder {
+	switch enc {
+	case EncodingUTF16LE:
+		return textunicode.UTF16(textunicode.LittleEndian, textunicode.UseBOM).NewDecoder()
+	case EncodingUTF16BE:
+		return textunicode.UTF16(textunicode.BigEndian, textunicode.UseBOM).NewDecoder()
+	case EncodingWindows1252:
+		return charmap.Windows1252.NewDecoder()
+	}
+	if cm := isoCharmap(enc); cm != nil {
+		return cm.NewDecoder()
+	}
+	return encoding.Nop.NewDecoder()
+}
+
+// isoCharmap returns the charmap of the named ISO-8859 encoding, or nil if
+// enc is not one.
+func isoCharmap(enc string) *charmap.Charmap {
+	switch enc {
+	case EncodingISO88591:
+		return charmap.ISO8859_1
+	case EncodingISO88592:
+		return charmap.ISO8859_2
+	case EncodingISO88595:
+		return charmap.ISO8859_5
+	case EncodingISO88597:
+		return charmap.ISO8859_7
+	case EncodingISO885915:
+		return charmap.ISO8859_15
+	}
+	return nil
+}
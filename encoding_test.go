@@ -0,0 +1,162 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16Bytes encodes s as UTF-16 in the given byte order, with a byte order
+// mark if bom is set.
+func utf16Bytes(s string, order binary.ByteOrder, bom bool) []byte {
+	var units []uint16
+	if bom {
+		units = append(units, 0xfeff)
+	}
+	units = append(units, utf16.Encode([]rune(s))...)
+	b := make([]byte, len(units)*2)
+	for i, u := range units {
+		order.PutUint16(b[i*2:], u)
+	}
+	return b
+}
+
+func TestDecodeText(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Bytes    []byte
+		Text     string
+		Encoding string
+		Error    error
+	}{
+		{
+			Name:     "utf-8",
+			Bytes:    []byte("Café crème"),
+			Text:     "Café crème",
+			Encoding: EncodingUTF8,
+		}, {
+			Name:     "utf-8 with bom",
+			Bytes:    []byte("\xef\xbb\xbfCafé"),
+			Text:     "\uFEFFCafé",
+			Encoding: EncodingUTF8,
+		}, {
+			Name:     "utf-16le with bom",
+			Bytes:    utf16Bytes("Café\r\n", binary.LittleEndian, true),
+			Text:     "Café\r\n",
+			Encoding: EncodingUTF16LE,
+		}, {
+			Name:     "utf-16be with bom",
+			Bytes:    utf16Bytes("Café\r\n", binary.BigEndian, true),
+			Text:     "Café\r\n",
+			Encoding: EncodingUTF16BE,
+		}, {
+			Name:     "utf-16le without bom",
+			Bytes:    utf16Bytes("Some plain text", binary.LittleEndian, false),
+			Text:     "Some plain text",
+			Encoding: EncodingUTF16LE,
+		}, {
+			Name:     "utf-16be without bom",
+			Bytes:    utf16Bytes("Some plain text", binary.BigEndian, false),
+			Text:     "Some plain text",
+			Encoding: EncodingUTF16BE,
+		}, {
+			Name:     "windows-1252",
+			Bytes:    []byte("\x93Caf\xe9\x94 \x96 5\x80"),
+			Text:     "“Café” – 5€",
+			Encoding: EncodingWindows1252,
+		}, {
+			Name:     "iso-8859-1",
+			Bytes:    []byte("Caf\xe9 cr\xe8me"),
+			Text:     "Café crème",
+			Encoding: EncodingISO88591,
+		}, {
+			Name:     "mostly utf-8",
+			Bytes:    []byte("caf\xc3\xa9 na\xefve"),
+			Text:     "café na\uFFFDve",
+			Encoding: EncodingUTF8,
+		}, {
+			Name:     "iso-8859-15",
+			Bytes:    []byte("Un c\xbdur \xe0 prendre"),
+			Text:     "Un cœur à prendre",
+			Encoding: EncodingISO885915,
+		}, {
+			Name:     "iso-8859-2",
+			Bytes:    []byte("\xa3\xf3d\xbc i Krak\xf3w"),
+			Text:     "Łódź i Kraków",
+			Encoding: EncodingISO88592,
+		}, {
+			Name:     "iso-8859-5",
+			Bytes:    []byte("\xbf\xe0\xd8\xd2\xd5\xe2, \xdc\xd8\xe0!"),
+			Text:     "Привет, мир!",
+			Encoding: EncodingISO88595,
+		}, {
+			Name:     "iso-8859-7",
+			Bytes:    []byte("\xc3\xe5\xe9\xdc \xf3\xef\xf5 \xea\xfc\xf3\xec\xe5"),
+			Text:     "Γειά σου κόσμε",
+			Encoding: EncodingISO88597,
+		}, {
+			Name:  "binary",
+			Bytes: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"),
+			Error: ErrInvalidContentType,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			text, enc, err := decodeText(tc.Bytes)
+			if err != tc.Error {
+				t.Fatalf("got error %v but expected %v", err, tc.Error)
+			}
+			if text != tc.Text {
+				t.Fatalf("got text %q but expected %q", text, tc.Text)
+			}
+			if enc != tc.Encoding {
+				t.Fatalf("got encoding %q but expected %q", enc, tc.Encoding)
+			}
+		})
+	}
+}
+
+func TestFromBytesEncoding(t *testing.T) {
+	b := utf16Bytes("# Notes\r\n\r\nCafé crème.\r\n", binary.LittleEndian, true)
+	p, err := fromBytes(b)
+	if err != nil {
+		t.Fatalf("failed to parse post: %v", err)
+	}
+	if p.Title != "Notes" {
+		t.Fatalf("got title %q but expected %q", p.Title, "Notes")
+	}
+	if p.Content != "Café crème.\n" {
+		t.Fatalf("got content %q but expected %q", p.Content, "Café crème.\n")
+	}
+}
+
+func TestReportEncoding(t *testing.T) {
+	a := getTestTar(t, fileList{
+		{"notepad.txt", string(utf16Bytes("Hello", binary.LittleEndian, true))},
+		{"latin.txt", "Caf\xe9"},
+	}, ".tar", compressions[0].Writer)
+	defer os.Remove(a)
+
+	r := &Report{}
+	_, err := FromTar(a, WithReport(r))
+	if err != nil {
+		t.Fatalf("failed to get posts from archive: %v", err)
+	}
+	if len(r.Files) != 2 {
+		t.Fatalf("got %d reported files but expected 2", len(r.Files))
+	}
+	if r.Files[0].Encoding != EncodingUTF16LE {
+		t.Fatalf("got encoding %q but expected %q", r.Files[0].Encoding, EncodingUTF16LE)
+	}
+	if r.Files[1].Encoding != EncodingISO88591 {
+		t.Fatalf("got encoding %q but expected %q", r.Files[1].Encoding, EncodingISO88591)
+	}
+}
@@ -6,14 +6,56 @@
 
 package wfimport
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	// ErrEmptyFile is returned when the file is empty
 	ErrEmptyFile = errors.New("file is empty")
-	// ErrInvalidContentType is returned when the detected content type does
-	// not match text/* as per net/http.DetectContentType
+	// ErrInvalidContentType is returned when the content of a file is not
+	// text, or no Parser is registered for it by extension or by its content
+	// type as per net/http.DetectContentType
 	ErrInvalidContentType = errors.New("invalid content type")
 	// ErrEmptyDir is returned when the directory is empty
 	ErrEmptyDir = errors.New("directory is empty")
+	// ErrStopWalk is returned by a WalkFunc to stop walking posts early, it
+	// is never returned by the walk itself
+	ErrStopWalk = errors.New("stop walk")
 )
+
+// FileError records an error reading or parsing a single file of an import,
+// such as ErrEmptyFile, along with the file it came from. Use errors.Is or
+// errors.As to inspect it. Importers that carry on past failing files, such
+// as FromDirectory, return them in the Errors of a *multierror.Error, which
+// errors.Is and errors.As look through too.
+type FileError struct {
+	// Path is the path of the file, or its name in the archive or fs.FS it
+	// was read from.
+	Path string
+	// Archive is the path of the archive the file was read from, empty for
+	// files that were not.
+	Archive string
+	Err     error
+}
+
+func (e *FileError) Error() string {
+	if e.Archive != "" {
+		return e.Archive + ": " + e.Path + ": " + e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// fileError wraps err in a FileError for the file at path, leaving nil and
+// context errors as they are so a cancelled import can be recognized.
+func fileError(path, archive string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &FileError{Path: path, Archive: archive, Err: err}
+}
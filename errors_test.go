@@ -0,0 +1,56 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/zip"
+	"errors"
+	"testing"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+func TestFileError(t *testing.T) {
+	a := getTestZip(t, filesWDirs)
+	_, err := FromZipDirsByFunc(a, func(f *zip.File) (*writeas.PostParams, error) {
+		if f.Name == "blog/post2.md" {
+			return nil, ErrEmptyDir
+		}
+		return TopLevelZipFunc(f)
+	}, WithErrorPolicy(FailFast))
+	if !errors.Is(err, ErrEmptyDir) {
+		t.Fatalf("got error %v but expected %v", err, ErrEmptyDir)
+	}
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) {
+		t.Fatalf("got error %#v but expected a FileError", err)
+	}
+	if fileErr.Path != "blog/post2.md" || fileErr.Archive != a {
+		t.Fatalf("got error for %s in %s but expected blog/post2.md in %s", fileErr.Path, fileErr.Archive, a)
+	}
+	expected := a + ": blog/post2.md: " + ErrEmptyDir.Error()
+	if err.Error() != expected {
+		t.Fatalf("got message %q but expected %q", err.Error(), expected)
+	}
+}
+
+func TestFileErrorWrapped(t *testing.T) {
+	// sentinel errors are still skipped when a ZipFunc wraps them
+	a := getTestZip(t, files)
+	posts, err := FromZipByFunc(a, func(f *zip.File) (*writeas.PostParams, error) {
+		if f.Name == "secret.txt" {
+			return nil, &FileError{Path: f.Name, Err: ErrEmptyFile}
+		}
+		return TopLevelZipFunc(f)
+	})
+	if err != nil {
+		t.Fatalf("failed to get posts from archive: %v", err)
+	}
+	if len(posts) != len(files)-1 {
+		t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), len(files)-1)
+	}
+}
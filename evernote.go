@@ -0,0 +1,289 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/writeas/go-writeas/v2"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// ENEXKey is the key for notes in a ZipCollections map read from an
+	// Evernote export without knowing the notebook they came from.
+	ENEXKey = "evernote"
+
+	enexDateLayout = "20060102T150405Z"
+)
+
+// enexNote is a single note from an Evernote ENEX export.
+type enexNote struct {
+	Title   string   `xml:"title"`
+	Content string   `xml:"content"`
+	Created string   `xml:"created"`
+	Updated string   `xml:"updated"`
+	Tags    []string `xml:"tag"`
+}
+
+// FromENEX reads an Evernote ENEX export from r and returns the notes it
+// contains keyed by ENEXKey, and an error if any. The ENML content of each
+// note is converted to Markdown with its tags appended as hashtags.
+// Attachments and encrypted text are left out. Notes that fail to be
+// converted are handled according to the ErrorPolicy.
+//
+// The export is read as a stream so large files are not loaded into memory
+// at once.
+func FromENEX(r io.Reader, opts ...Option) (ZipCollections, error) {
+	return fromENEXReader(r, newOptions(opts))
+}
+
+// FromENEXContext works as FromENEX, checking ctx between notes and while
+// converting them. Once ctx is done the notes read until then are returned
+// along with ctx.Err().
+func FromENEXContext(ctx context.Context, r io.Reader, opts ...Option) (ZipCollections, error) {
+	return fromENEXReader(r, contextOptions(ctx, opts))
+}
+
+func fromENEXReader(r io.Reader, o *options) (ZipCollections, error) {
+	out := ZipCollections{}
+	postErrors, err := fromENEX(r, "", ENEXKey, o, out)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return out, err
+}
+
+// FromENEXNotebooks reads the Evernote ENEX exports at paths, one for each
+// notebook as Evernote writes them when exporting several notebooks, and
+// returns their notes keyed by notebook. The notebook is named after the
+// file, so that the notes in Travel.enex are keyed by Travel. Notes that
+// fail to be converted and exports that fail to be read, which are then
+// left out whole, are handled according to the ErrorPolicy.
+func FromENEXNotebooks(paths []string, opts ...Option) (ZipCollections, error) {
+	return fromENEXNotebooks(paths, newOptions(opts))
+}
+
+// FromENEXNotebooksContext works as FromENEXNotebooks, stopping once ctx is
+// done as FromENEXContext does.
+func FromENEXNotebooksContext(ctx context.Context, paths []string, opts ...Option) (ZipCollections, error) {
+	return fromENEXNotebooks(paths, contextOptions(ctx, opts))
+}
+
+func fromENEXNotebooks(paths []string, o *options) (ZipCollections, error) {
+	var postErrors error
+	out := ZipCollections{}
+	for _, path := range paths {
+		notes := ZipCollections{}
+		noteErrors, err := fromENEXFile(path, o, notes)
+		if err == nil || canceled(o.ctx, err) {
+			for key, posts := range notes {
+				out[key] = append(out[key], posts...)
+			}
+			if noteErrors != nil {
+				postErrors = multierror.Append(postErrors, noteErrors)
+			}
+		}
+		if canceled(o.ctx, err) {
+			return out, err
+		}
+		// err is either a failure to read the export or, with FailFast, the
+		// first note that failed, which has been through the policy already
+		if err := o.fileFailed(&postErrors, err); err != nil {
+			return nil, err
+		}
+	}
+	return out, postErrors
+}
+
+func fromENEXFile(path string, o *options, out ZipCollections) (postErrors, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		o.record(path, "", nil, err)
+		return nil, fileError(path, "", err)
+	}
+	defer f.Close()
+
+	notebook := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return fromENEX(f, path, notebook, o, out)
+}
+
+// fromENEX adds the notes read from r, the export at path or an io.Reader if
+// path is empty, to out under key, or the collection of o if set. Errors converting single notes are
+// gathered into postErrors, as the error policy allows, while err is set if
+// the export could not be read or the import was aborted.
+func fromENEX(r io.Reader, path, key string, o *options, out ZipCollections) (postErrors, err error) {
+	failed := func(err error) error {
+		if path == "" {
+			return err
+		}
+		o.record(path, "", nil, err)
+		return fileError(path, "", err)
+	}
+	key = o.collectionKey(key)
+
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	for i := 1; ; {
+		if err := o.ctx.Err(); err != nil {
+			return postErrors, err
+		}
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return postErrors, failed(err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "note" {
+			continue
+		}
+
+		note := enexNote{}
+		if err := d.DecodeElement(&note, &se); err != nil {
+			return postErrors, failed(err)
+		}
+		id := strings.TrimSpace(note.Title)
+		if id == "" {
+			id = strconv.Itoa(i)
+		}
+		i++
+		p, err := note.post(o.ctx)
+		if canceled(o.ctx, err) {
+			return postErrors, err
+		}
+		if err == nil && p == nil {
+			err = ErrEmptyFile
+		}
+		o.recordPost(path, id, p, err)
+		if skipped(err) {
+			continue
+		} else if err != nil {
+			if err := o.postFailed(&postErrors, path, id, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		p.Collection = key
+		out[key] = append(out[key], p)
+	}
+	return postErrors, nil
+}
+
+// post converts the note into a post, or returns nil if it has no content.
+func (note enexNote) post(ctx context.Context) (*writeas.PostParams, error) {
+	body, err := enmlToMarkdown(ctx, note.Content)
+	if err != nil {
+		return nil, err
+	}
+	if ht := hashtags(note.Tags); ht != "" {
+		body = strings.TrimSpace(body + "\n\n" + ht)
+	}
+	if body == "" {
+		return nil, nil
+	}
+
+	p := &writeas.PostParams{
+		Title:   strings.TrimSpace(note.Title),
+		Content: body,
+		Created: enexTime(note.Created),
+		Updated: enexTime(note.Updated),
+	}
+	return p, nil
+}
+
+// enmlToMarkdown converts the ENML document s, the XHTML dialect Evernote
+// stores notes in, into Markdown.
+func enmlToMarkdown(ctx context.Context, s string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	root := findNode(doc, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "en-note"
+	})
+	if root == nil {
+		return "", nil
+	}
+	cleanENML(root)
+	return htmlToMarkdownContext(ctx, root, nil)
+}
+
+// cleanENML rewrites the Evernote specific elements under n for conversion,
+// turning en-todo checkboxes into HTML ones and dropping
+// attachments and encrypted text. As the HTML parser does not know these
+// elements to be empty, the content following en-todo and en-media ends up
+// inside of them and is moved back out.
+func cleanENML(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type != html.ElementNode {
+			cleanENML(child)
+			child = next
+			continue
+		}
+		switch child.Data {
+		case "en-todo":
+			box := &html.Node{
+				Type:     html.ElementNode,
+				Data:     "input",
+				DataAtom: atom.Input,
+				Attr:     []html.Attribute{{Key: "type", Val: "checkbox"}},
+			}
+			if strings.EqualFold(attr(child, "checked"), "true") {
+				box.Attr = append(box.Attr, html.Attribute{Key: "checked"})
+			}
+			n.InsertBefore(box, child)
+			next = hoistChildren(child)
+		case "en-media":
+			next = hoistChildren(child)
+		case "en-crypt":
+			n.RemoveChild(child)
+		default:
+			cleanENML(child)
+		}
+		child = next
+	}
+}
+
+// hoistChildren replaces n with its children and returns the first of them,
+// or the node that followed n if it had none.
+func hoistChildren(n *html.Node) *html.Node {
+	parent, next := n.Parent, n.NextSibling
+	first := n.FirstChild
+	for c := n.FirstChild; c != nil; c = n.FirstChild {
+		n.RemoveChild(c)
+		parent.InsertBefore(c, n)
+	}
+	parent.RemoveChild(n)
+	if first != nil {
+		return first
+	}
+	return next
+}
+
+// enexTime parses an Evernote timestamp, or returns nil if it is missing or
+// malformed.
+func enexTime(s string) *time.Time {
+	t, err := time.Parse(enexDateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
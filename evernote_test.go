@@ -0,0 +1,169 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const enex = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export export-date="20200301T100000Z" application="Evernote" version="10.0">
+	<note>
+		<title>Packing List</title>
+		<created>20200105T093000Z</created>
+		<updated>20200106T180000Z</updated>
+		<tag>travel</tag>
+		<tag>to do</tag>
+		<content><![CDATA[<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd">
+<en-note><div>Things to <b>bring</b>:</div><div><en-todo checked="true"/>Passport</div><div><en-todo/>Charger</div><div><br/></div><div><en-media type="image/png" hash="abc"/>Map above.</div><div><en-crypt hint="pin">c2VjcmV0</en-crypt></div></en-note>]]></content>
+	</note>
+	<note>
+		<title>Empty</title>
+		<created>20200107T093000Z</created>
+		<content><![CDATA[<en-note><div><br/></div></en-note>]]></content>
+	</note>
+</en-export>`
+
+func TestFromENEX(t *testing.T) {
+	colls, err := FromENEX(strings.NewReader(enex))
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	posts := colls[ENEXKey]
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts but expected 1", len(posts))
+	}
+	p := posts[0]
+	if p.Title != "Packing List" {
+		t.Fatalf("got title %q but expected %q", p.Title, "Packing List")
+	}
+	expected := "Things to **bring**:\n\n[x] Passport\n\n[ ] Charger\n\nMap above.\n\n#Travel #ToDo"
+	if p.Content != expected {
+		t.Fatalf("got content %q but expected %q", p.Content, expected)
+	}
+	if p.Collection != ENEXKey {
+		t.Fatalf("got collection %q but expected %q", p.Collection, ENEXKey)
+	}
+	created := time.Date(2020, 1, 5, 9, 30, 0, 0, time.UTC)
+	if p.Created == nil || !p.Created.Equal(created) {
+		t.Fatalf("got created %v but expected %v", p.Created, created)
+	}
+	updated := time.Date(2020, 1, 6, 18, 0, 0, 0, time.UTC)
+	if p.Updated == nil || !p.Updated.Equal(updated) {
+		t.Fatalf("got updated %v but expected %v", p.Updated, updated)
+	}
+
+	r := &Report{}
+	_, err = FromENEX(strings.NewReader(enex), WithReport(r))
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	if len(r.Files) != 2 {
+		t.Fatalf("got %d reported notes but expected 2", len(r.Files))
+	}
+	if r.Files[0].Path != "Packing List" || r.Files[0].Outcome != Imported {
+		t.Fatalf("got %s %v but expected Packing List to be imported", r.Files[0].Path, r.Files[0].Outcome)
+	}
+	if r.Files[1].Path != "Empty" || r.Files[1].Outcome != SkippedEmpty {
+		t.Fatalf("got %s %v but expected Empty to be skipped", r.Files[1].Path, r.Files[1].Outcome)
+	}
+
+	_, err = FromENEX(strings.NewReader(enex[:len(enex)/2] + "<note><title>"))
+	if err == nil {
+		t.Fatal("got no error but expected the truncated export to fail")
+	}
+}
+
+func TestFromENEXNotebooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enex")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var paths []string
+	for _, name := range []string{"Travel.enex", "Work.enex"} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(enex), 0644); err != nil {
+			t.Fatalf("failed to write export: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	colls, err := FromENEXNotebooks(paths)
+	if err != nil {
+		t.Fatalf("failed to get posts from exports: %v", err)
+	}
+	for _, notebook := range []string{"Travel", "Work"} {
+		if len(colls[notebook]) != 1 {
+			t.Fatalf("got %d posts in %s but expected 1", len(colls[notebook]), notebook)
+		}
+		if colls[notebook][0].Collection != notebook {
+			t.Fatalf("got collection %q but expected %q", colls[notebook][0].Collection, notebook)
+		}
+	}
+
+	broken := filepath.Join(dir, "Broken.enex")
+	if err := ioutil.WriteFile(broken, []byte(enex[:len(enex)/2]+"<note><title>"), 0644); err != nil {
+		t.Fatalf("failed to write export: %v", err)
+	}
+	paths = append(paths, filepath.Join(dir, "Missing.enex"), broken)
+	colls, err = FromENEXNotebooks(paths)
+	merr, ok := err.(*multierror.Error)
+	if !ok || len(merr.Errors) != 2 {
+		t.Fatalf("got error %v but expected two collected errors", err)
+	}
+	if !os.IsNotExist(merr.Errors[0].(*FileError).Err) {
+		t.Fatalf("got error %v but expected the file not to exist", merr.Errors[0])
+	}
+	if len(colls["Travel"]) != 1 || len(colls["Work"]) != 1 || colls["Broken"] != nil {
+		t.Fatalf("got %v but expected the notes of the valid exports alone", colls)
+	}
+
+	r := &Report{}
+	_, err = FromENEXNotebooks(paths, WithReport(r), WithErrorPolicy(SkipSilently))
+	if err != nil {
+		t.Fatalf("got error %v but expected none", err)
+	}
+	outcomes := map[string]Outcome{}
+	for _, fr := range r.Files {
+		outcomes[filepath.Base(fr.Path)] = fr.Outcome
+	}
+	if outcomes["Travel.enex#Packing List"] != Imported || outcomes["Work.enex#Empty"] != SkippedEmpty {
+		t.Fatalf("got outcomes %v but expected the notes to be reported", outcomes)
+	}
+	if outcomes["Missing.enex"] != Failed || outcomes["Broken.enex"] != Failed {
+		t.Fatalf("got outcomes %v but expected the failing exports to be reported", outcomes)
+	}
+
+	colls, err = FromENEXNotebooks(paths, WithErrorPolicy(FailFast))
+	if _, ok := err.(*FileError); !ok || colls != nil {
+		t.Fatalf("got %v and error %v but expected a *FileError alone", colls, err)
+	}
+}
+
+func TestFromENEXContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	colls, err := FromENEXContext(ctx, strings.NewReader(enex))
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls[ENEXKey]) != 0 {
+		t.Fatalf("got %d posts but expected 0", len(colls[ENEXKey]))
+	}
+}
@@ -7,14 +7,14 @@
 package wfimport
 
 import (
-	"io/ioutil"
-	"net/http"
+	"context"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
-	"github.com/hashicorp/go-multierror"
 	"github.com/writeas/go-writeas/v2"
 )
 
@@ -23,20 +23,40 @@ import (
 //
 // The pattern should be a valid regex, for more details see
 // https://golang.org/s/re2syntax or run `go doc regexp/syntax`
-func FromDirectoryMatch(path, pattern string) ([]*writeas.PostParams, error) {
-	return fromDirectory(path, pattern)
+func FromDirectoryMatch(path, pattern string, opts ...Option) ([]*writeas.PostParams, error) {
+	return fromDirectory(path, pattern, newOptions(opts))
+}
+
+// FromDirectoryMatchContext works as FromDirectoryMatch, checking ctx between
+// files. Once ctx is done the posts read until then are returned along with
+// ctx.Err().
+func FromDirectoryMatchContext(ctx context.Context, path, pattern string, opts ...Option) ([]*writeas.PostParams, error) {
+	return fromDirectory(path, pattern, contextOptions(ctx, opts))
 }
 
 // FromDirectory reads all text and markdown files in path and returns the
-// parsed posts and an error if any.
-func FromDirectory(path string) ([]*writeas.PostParams, error) {
-	return fromDirectory(path, "")
+// parsed posts and an error if any. Empty and binary files are left out and,
+// unlike in the other imports, handled as failing files by the ErrorPolicy,
+// ErrEmptyFile and ErrInvalidContentType being returned for them by default.
+func FromDirectory(path string, opts ...Option) ([]*writeas.PostParams, error) {
+	return fromDirectory(path, "", newOptions(opts))
+}
+
+// FromDirectoryContext works as FromDirectory, checking ctx between files.
+// Once ctx is done the posts read until then are returned along with
+// ctx.Err().
+func FromDirectoryContext(ctx context.Context, path string, opts ...Option) ([]*writeas.PostParams, error) {
+	return fromDirectory(path, "", contextOptions(ctx, opts))
 }
 
 // fromDirectory takes an 'optional' pattern, if an empty string is passed
-// all valid txt and md files will be included under path.
+// the WithMatch pattern or, without one, all valid txt and md files will be
+// included under path.
 // Otherwise pattern should be a valid regex per MatchFromDirectory
-func fromDirectory(path, pattern string) ([]*writeas.PostParams, error) {
+func fromDirectory(path, pattern string, o *options) ([]*writeas.PostParams, error) {
+	if pattern == "" {
+		pattern = o.pattern
+	}
 	if pattern == "" {
 		pattern = "."
 	}
@@ -44,7 +64,11 @@ func fromDirectory(path, pattern string) ([]*writeas.PostParams, error) {
 	if err != nil {
 		return nil, err
 	}
-	list, err := ioutil.ReadDir(path)
+	fsys, err := dirFS(path)
+	if err != nil {
+		return nil, err
+	}
+	list, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return nil, err
 	}
@@ -52,80 +76,212 @@ func fromDirectory(path, pattern string) ([]*writeas.PostParams, error) {
 		return nil, ErrEmptyDir
 	}
 
+	var filenames []string
+	for _, f := range list {
+		if !f.IsDir() && rx.MatchString(f.Name()) {
+			filenames = append(filenames, f.Name())
+		}
+	}
+
 	var postErrors error
 	posts := []*writeas.PostParams{}
-	for _, f := range list {
-		if !f.IsDir() {
-			filename := f.Name()
-			if rx.MatchString(filename) {
-				post, err := FromFile(filepath.Join(path, filename))
-				if err != nil {
-					postErrors = multierror.Append(postErrors, err)
-					continue
-				}
-
-				posts = append(posts, post)
-			}
+	encs := make([]string, len(filenames))
+	err = parseOrdered(o.ctx, o.workers, len(filenames), func(i int) (*writeas.PostParams, error) {
+		post, _, enc, err := fromFSFile(fsys, filenames[i])
+		encs[i] = enc
+		return post, err
+	}, func(i int, post *writeas.PostParams, err error) error {
+		o.record(filenames[i], encs[i], post, err)
+		if err != nil {
+			return o.fileFailed(&postErrors, fileError(filepath.Join(path, filenames[i]), "", err))
 		}
+		o.applyTitle(filenames[i], post)
+		posts = append(posts, post)
+		return nil
+	})
+	if canceled(o.ctx, err) {
+		return posts, err
+	} else if err != nil {
+		return nil, err
 	}
 	return posts, postErrors
 }
 
+// FromDirectoryTree reads all text and markdown files under path, including
+// those in subdirectories, and returns the parsed posts and an error if any.
+// It works as FromFS does on the directory at path.
+func FromDirectoryTree(path string, opts ...Option) (ZipCollections, error) {
+	fsys, err := dirFS(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromFS(fsys, ".", opts...)
+}
+
+// FromDirectoryTreeContext works as FromDirectoryTree, checking ctx between
+// files. Once ctx is done the collections read until then are returned along
+// with ctx.Err().
+func FromDirectoryTreeContext(ctx context.Context, path string, opts ...Option) (ZipCollections, error) {
+	fsys, err := dirFS(path)
+	if err != nil {
+		return nil, err
+	}
+	return fromFS(fsys, ".", contextOptions(ctx, opts))
+}
+
 // FromFile reads in a file from path and returns the parsed post and an error
 // if any. The title will be extracted from the first markdown level 1 header.
+//
+// YAML (---), TOML (+++) and JSON ({...}) front matter at the top of the file
+// is parsed and removed from the body. The keys title, slug, date, lastmod,
+// lang, rtl and font are mapped onto the post, and draft: true leaves the
+// post without a collection. The file's modification time is only used when
+// no date was given.
+//
+// Other titles can be found in plain text files with WithTitleStrategy.
 // TODO: consider using filenameParts to get ID, coll and slug. This would
 // produce unpredictable results with user created files however.
-func FromFile(path string) (*writeas.PostParams, error) {
-	b, err := ioutil.ReadFile(path)
+func FromFile(path string, opts ...Option) (*writeas.PostParams, error) {
+	p, _, _, err := fromFSFile(os.DirFS(filepath.Dir(path)), filepath.Base(path))
 	if err != nil {
-		return nil, err
+		return nil, fileError(path, "", err)
 	}
+	newOptions(opts).applyTitle(path, p)
+	return p, nil
+}
 
-	info, err := os.Stat(path)
-	if err != nil {
+// FromFileContext works as FromFile, returning ctx.Err() without reading the
+// file if ctx is already done.
+func FromFileContext(ctx context.Context, path string, opts ...Option) (*writeas.PostParams, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	return FromFile(path, opts...)
+}
 
-	p, err := fromBytes(b)
-	if err != nil {
+// dirFS returns the file system for the directory at path, checking first
+// that it exists.
+func dirFS(path string) (fs.FS, error) {
+	if _, err := os.Stat(path); err != nil {
 		return nil, err
 	}
-	created := info.ModTime()
-	p.Created = &created
+	return os.DirFS(path), nil
+}
 
-	return p, nil
+// parsePost parses the contents b of the file name with the parser registered
+// for it, using modTime as the creation date of the post when the content did
+// not set one.
+func parsePost(name string, b []byte, modTime time.Time) (p *writeas.PostParams, fm *frontMatter, enc string, err error) {
+	p, fm, enc, err = parseFile(name, b)
+	if err != nil {
+		return nil, nil, enc, err
+	}
+	if p.Created == nil {
+		p.Created = &modTime
+	}
+	return p, fm, enc, nil
 }
 
+// fromBytes parses b into a post if its content is sniffed to be text, there
+// being no file name to choose a parser by.
 func fromBytes(b []byte) (*writeas.PostParams, error) {
+	if len(b) > 0 && !strings.HasPrefix(sniffMediaType(b), "text/") {
+		return nil, ErrInvalidContentType
+	}
+	p, _, _, err := parseBytes(b)
+	return p, err
+}
+
+// parseBytes parses b into a post, applying any front matter found at the top
+// of the content. The front matter is returned as well so callers can act on
+// values that have no PostParams equivalent, it is nil if there was none.
+// Content in other encodings is transcoded to UTF-8 first, enc is the name of
+// the encoding it was detected to be in.
+func parseBytes(b []byte) (p *writeas.PostParams, fm *frontMatter, enc string, err error) {
 	if len(b) == 0 {
-		return nil, ErrEmptyFile
+		return nil, nil, "", ErrEmptyFile
 	}
 
-	if contentType := http.DetectContentType(b); !strings.HasPrefix(contentType, "text/") {
-		return nil, ErrInvalidContentType
+	text, enc, err := decodeText(b)
+	if err != nil {
+		return nil, nil, enc, err
+	}
+	text = normalizeText(text)
+	if text == "" {
+		return nil, nil, enc, ErrEmptyFile
+	}
+
+	fm, content, err := extractFrontMatter(text)
+	if err != nil {
+		return nil, nil, enc, err
 	}
 
-	title, body := extractTitle(string(b))
-	post := writeas.PostParams{
-		Title:   title,
-		Content: body,
+	post := writeas.PostParams{}
+	if fm != nil && fm.Title != "" {
+		post.Content = content
+	} else {
+		post.Title, post.Content = extractTitle(content)
 	}
+	if fm != nil {
+		fm.apply(&post)
+	}
+
+	return &post, fm, enc, nil
+}
 
-	return &post, nil
+// normalizeText strips a leading UTF-8 byte order mark from s and turns
+// Windows and classic Mac OS line endings into \n.
+func normalizeText(s string) string {
+	s = strings.TrimPrefix(s, "\uFEFF")
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	return strings.Replace(s, "\r", "\n", -1)
 }
 
-// TODO: copied from writeas/web-core/posts due to errors with package imports
-// maybe also find a way to grab the first line as a title in plain text files
+// extractTitle returns the text of a level 1 Markdown heading at the top of
+// content as the title, along with the rest of content as the body. Blank
+// lines before the heading, up to three spaces of indentation, a missing
+// space after the # and a closing sequence of #s are all accepted. Without
+// a heading the title is empty and the body is content unchanged.
+//
+// Titles of plain text files are left to the TitleStrategy of the import.
 func extractTitle(content string) (title string, body string) {
-	if hashIndex := strings.Index(content, "# "); hashIndex == 0 {
-		eol := strings.IndexRune(content, '\n')
-		// First line should start with # and end with \n
-		if eol != -1 {
-			body = strings.TrimLeft(content[eol:], " \t\n\r")
-			title = content[len("# "):eol]
-			return
+	rest := content
+	for {
+		eol := strings.IndexByte(rest, '\n')
+		if eol == -1 || strings.TrimSpace(rest[:eol]) != "" {
+			break
 		}
+		rest = rest[eol+1:]
+	}
+
+	line := rest
+	if eol := strings.IndexByte(rest, '\n'); eol != -1 {
+		line, rest = rest[:eol], rest[eol+1:]
+	} else {
+		rest = ""
+	}
+	title, ok := atxHeading(line)
+	if !ok {
+		return "", content
+	}
+	return title, strings.TrimLeft(rest, " \t\n")
+}
+
+// atxHeading returns the text of line if it is a level 1 ATX heading, such
+// as "# Title", "#Title" or "# Title #".
+func atxHeading(line string) (string, bool) {
+	text := strings.TrimLeft(line, " ")
+	if len(line)-len(text) > 3 || !strings.HasPrefix(text, "#") || strings.HasPrefix(text, "##") {
+		return "", false
+	}
+	text = strings.TrimSpace(text[1:])
+	if closed := strings.TrimRight(text, "#"); closed != text {
+		if closed == "" || strings.HasSuffix(closed, " ") || strings.HasSuffix(closed, "\t") {
+			text = strings.TrimSpace(closed)
+		}
+	}
+	if text == "" {
+		return "", false
 	}
-	body = content
-	return
+	return text, true
 }
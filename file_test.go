@@ -7,7 +7,10 @@
 package wfimport
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -190,13 +193,83 @@ func TestFromDirectoryErrors(t *testing.T) {
 		}
 		f.Close()
 	}
-	posts, err := FromDirectory(testDir)
+	r := &Report{}
+	posts, err := FromDirectory(testDir, WithReport(r))
 	if err == nil {
 		t.Fatal("error was nil but no files have contents")
 	}
 	if len(posts) == len(files) {
 		t.Fatal("files with errors were returned, should be skipped")
 	}
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) || !errors.Is(err, ErrEmptyFile) {
+		t.Fatalf("got error %v but expected a FileError for an empty file", err)
+	}
+	if len(r.Files) != len(files) {
+		t.Fatalf("got %d reported files but expected %d", len(r.Files), len(files))
+	}
+	for _, fr := range r.Files {
+		if fr.Outcome != SkippedEmpty {
+			t.Fatalf("got outcome %v for %s but expected %v", fr.Outcome, fr.Path, SkippedEmpty)
+		}
+	}
+}
+
+func TestFromDirectoryContext(t *testing.T) {
+	testDir := "test"
+	files := []string{"test.md", "test2.txt", "test3"}
+
+	err := os.Mkdir(testDir, os.ModeDir|os.ModePerm)
+	defer os.RemoveAll(testDir)
+	if err != nil {
+		t.Fatalf("failed to create base test dir: %v", err)
+	}
+	for _, fn := range files {
+		err := ioutil.WriteFile(filepath.Join(testDir, fn), []byte("some content"), 0644)
+		if err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	posts, err := FromDirectoryContext(ctx, testDir)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(posts) != 0 {
+		t.Fatalf("got %d posts but expected none", len(posts))
+	}
+	posts, err = FromDirectoryMatchContext(ctx, testDir, `\.md$`)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(posts) != 0 {
+		t.Fatalf("got %d posts but expected none", len(posts))
+	}
+	_, err = FromDirectoryTreeContext(ctx, testDir)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	_, err = FromFileContext(ctx, filepath.Join(testDir, files[0]))
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+
+	posts, err = FromDirectoryContext(context.Background(), testDir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(posts) != len(files) {
+		t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), len(files))
+	}
+	posts, err = FromDirectoryMatchContext(context.Background(), testDir, `\.md$`)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), 1)
+	}
 }
 
 func TestFromFile(t *testing.T) {
@@ -266,8 +339,12 @@ func TestFromFileEmptyBody(t *testing.T) {
 	}()
 
 	post, err := FromFile(testFile)
-	if err != ErrEmptyFile {
-		t.Fatalf("error was nil but should error with empty file")
+	if !errors.Is(err, ErrEmptyFile) {
+		t.Fatalf("got error %v but expected %v", err, ErrEmptyFile)
+	}
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) || fileErr.Path != testFile {
+		t.Fatalf("got error %#v but expected a FileError for %s", err, testFile)
 	}
 	if post != nil {
 		t.Fatal("post was returned but should be nil")
@@ -312,3 +389,155 @@ func TestFromBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestFromDirectoryTree(t *testing.T) {
+	testDir := "test"
+	files := map[string]string{
+		"draft.md":              "# Top level\n\na draft",
+		"blog/post.md":          "# Blog post\n\nin the blog",
+		"blog/2019/old.txt":     "an older post",
+		"blog/2019/hidden.md":   "---\ndraft: true\n---\nnot ready",
+		"notes/empty.txt":       "",
+		".git/HEAD":             "ref: refs/heads/master",
+		"notes/.hidden/file.md": "hidden",
+	}
+
+	for fn, contents := range files {
+		fp := filepath.Join(testDir, filepath.FromSlash(fn))
+		err := os.MkdirAll(filepath.Dir(fp), os.ModeDir|os.ModePerm)
+		if err != nil {
+			t.Fatalf("failed to create test dir: %v", err)
+		}
+		err = ioutil.WriteFile(fp, []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+	}
+	defer os.RemoveAll(testDir)
+
+	colls, err := FromDirectoryTree(testDir)
+	if err != nil {
+		t.Fatalf("failed to parse directory tree: %v", err)
+	}
+	if len(colls) != 3 {
+		t.Fatalf("collection count mismatch: got %d, expecting 3", len(colls))
+	}
+	if len(colls[DraftsKey]) != 2 {
+		t.Fatalf("draft count mismatch: got %d, expecting 2", len(colls[DraftsKey]))
+	}
+	if len(colls["blog"]) != 2 {
+		t.Fatalf("blog count mismatch: got %d, expecting 2", len(colls["blog"]))
+	}
+	for _, p := range colls["blog"] {
+		if p.Collection != "blog" {
+			t.Fatalf("got collection %q but expected blog", p.Collection)
+		}
+	}
+	if colls["notes"] == nil || len(colls["notes"]) != 0 {
+		t.Fatalf("notes should be an empty collection, got %v", colls["notes"])
+	}
+
+	colls, err = FromDirectoryTree(testDir, WithMatch(`\.md$`))
+	if err != nil {
+		t.Fatalf("failed to parse directory tree: %v", err)
+	}
+	if len(colls["blog"]) != 1 {
+		t.Fatalf("blog count mismatch: got %d, expecting 1", len(colls["blog"]))
+	}
+}
+
+func TestExtractTitle(t *testing.T) {
+	tt := []struct {
+		Name  string
+		Bytes string
+		Title string
+		Body  string
+	}{
+		{
+			Name:  "heading",
+			Bytes: "# Title\n\nBody text.",
+			Title: "Title",
+			Body:  "Body text.",
+		}, {
+			Name:  "no heading",
+			Bytes: "Just text.\n\nMore text.",
+			Title: "",
+			Body:  "Just text.\n\nMore text.",
+		}, {
+			Name:  "byte order mark",
+			Bytes: "\uFEFF# Title\n\nBody text.",
+			Title: "Title",
+			Body:  "Body text.",
+		}, {
+			Name:  "windows line endings",
+			Bytes: "# Title\r\n\r\nBody text.\r\nSecond line.",
+			Title: "Title",
+			Body:  "Body text.\nSecond line.",
+		}, {
+			Name:  "notepad",
+			Bytes: "\uFEFF# Title\r\n\r\nBody text.\r\n",
+			Title: "Title",
+			Body:  "Body text.\n",
+		}, {
+			Name:  "leading blank lines",
+			Bytes: "\n  \n# Title\nBody text.",
+			Title: "Title",
+			Body:  "Body text.",
+		}, {
+			Name:  "no space after hash",
+			Bytes: "#Title\n\nBody text.",
+			Title: "Title",
+			Body:  "Body text.",
+		}, {
+			Name:  "closing hashes",
+			Bytes: "# Title ##\n\nBody text.",
+			Title: "Title",
+			Body:  "Body text.",
+		}, {
+			Name:  "hash in title",
+			Bytes: "# Issue #42\n\nBody text.",
+			Title: "Issue #42",
+			Body:  "Body text.",
+		}, {
+			Name:  "indented heading",
+			Bytes: "   # Title\n\nBody text.",
+			Title: "Title",
+			Body:  "Body text.",
+		}, {
+			Name:  "code block",
+			Bytes: "    # comment\n\nBody text.",
+			Title: "",
+			Body:  "    # comment\n\nBody text.",
+		}, {
+			Name:  "level 2 heading",
+			Bytes: "## Section\n\nBody text.",
+			Title: "",
+			Body:  "## Section\n\nBody text.",
+		}, {
+			Name:  "heading only",
+			Bytes: "# Title",
+			Title: "Title",
+			Body:  "",
+		}, {
+			Name:  "front matter with windows line endings",
+			Bytes: "\uFEFF---\r\nslug: a-post\r\n---\r\n# Title\r\n\r\nBody text.",
+			Title: "Title",
+			Body:  "Body text.",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, err := fromBytes([]byte(tc.Bytes))
+			if err != nil {
+				t.Fatalf("failed to parse post: %v", err)
+			}
+			if p.Title != tc.Title {
+				t.Fatalf("got title %q but expected %q", p.Title, tc.Title)
+			}
+			if p.Content != tc.Body {
+				t.Fatalf("got body %q but expected %q", p.Content, tc.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/writeas/go-writeas/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// frontMatterKeyRx matches a front matter block starting with a key, as in
+// title: or title =.
+var frontMatterKeyRx = regexp.MustCompile(`^\s*[\w-]+\s*[:=]`)
+
+const (
+	yamlDelim = "---"
+	tomlDelim = "+++"
+)
+
+// frontMatterDateLayouts are the date formats accepted for the date and
+// lastmod keys, covering what Jekyll, Hugo and Zola write by default.
+var frontMatterDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// frontMatter holds the metadata parsed from the front matter block at the
+// top of a file. Only the keys that have an equivalent in writeas.PostParams
+// are kept.
+type frontMatter struct {
+	Title   string
+	Slug    string
+	Date    *time.Time
+	Lastmod *time.Time
+	Lang    *string
+	RTL     *bool
+	Font    string
+	Draft   bool
+}
+
+// apply copies the front matter values onto p, leaving fields that were not
+// set in the front matter untouched.
+func (fm *frontMatter) apply(p *writeas.PostParams) {
+	if fm.Title != "" {
+		p.Title = fm.Title
+	}
+	if fm.Slug != "" {
+		p.Slug = fm.Slug
+	}
+	if fm.Date != nil {
+		p.Created = fm.Date
+	}
+	if fm.Lastmod != nil {
+		p.Updated = fm.Lastmod
+	}
+	if fm.Lang != nil {
+		p.Language = fm.Lang
+	}
+	if fm.RTL != nil {
+		p.IsRTL = fm.RTL
+	}
+	if fm.Font != "" {
+		p.Font = fm.Font
+	}
+}
+
+// extractFrontMatter looks for a YAML (---), TOML (+++) or JSON ({...}) front
+// matter block at the start of content. It returns the parsed front matter and
+// the remaining body, or a nil frontMatter and the unchanged content if there
+// is no front matter.
+//
+// A block that does not hold a mapping is taken to be part of the body, such
+// as text between two --- thematic breaks, unless it starts with a key and
+// was clearly meant as front matter.
+func extractFrontMatter(content string) (*frontMatter, string, error) {
+	var (
+		meta map[string]interface{}
+		body string
+		err  error
+	)
+	switch {
+	case strings.HasPrefix(content, yamlDelim+"\n"), strings.HasPrefix(content, yamlDelim+"\r\n"):
+		var block string
+		block, body = splitFrontMatter(content, yamlDelim, "...")
+		if body == content {
+			return nil, content, nil
+		}
+		meta, err = decodeYAMLFrontMatter(block)
+		if meta == nil && err == nil {
+			return nil, content, nil
+		}
+	case strings.HasPrefix(content, tomlDelim+"\n"), strings.HasPrefix(content, tomlDelim+"\r\n"):
+		var block string
+		block, body = splitFrontMatter(content, tomlDelim)
+		if body == content {
+			return nil, content, nil
+		}
+		meta = map[string]interface{}{}
+		if _, err = toml.Decode(block, &meta); err != nil && !frontMatterKeyRx.MatchString(block) {
+			return nil, content, nil
+		}
+	case strings.HasPrefix(content, "{"):
+		meta, body, err = splitJSONFrontMatter(content)
+		if err != nil || strings.TrimSpace(body) == "" {
+			// a leading brace alone is not enough to assume front matter, nor
+			// is a file that is a JSON object as a whole
+			return nil, content, nil
+		}
+	default:
+		return nil, content, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("parsing front matter: %v", err)
+	}
+
+	return newFrontMatter(meta), strings.TrimLeft(body, " \t\r\n"), nil
+}
+
+// decodeYAMLFrontMatter decodes the YAML front matter block. It returns a nil
+// map and error if the block is not a mapping, and only returns an error if it
+// fails to decode a block starting with a key.
+func decodeYAMLFrontMatter(block string) (map[string]interface{}, error) {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(block), &v); err != nil {
+		if frontMatterKeyRx.MatchString(block) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	switch v.(type) {
+	case nil:
+		// an empty block, as Jekyll uses to mark files to be processed
+		return map[string]interface{}{}, nil
+	case map[interface{}]interface{}:
+		meta := map[string]interface{}{}
+		return meta, yaml.Unmarshal([]byte(block), &meta)
+	}
+	return nil, nil
+}
+
+// splitFrontMatter splits content on the first line matching one of the
+// closing delimiters after the opening delimiter line. If no closing delimiter
+// is found content is returned unchanged as the body.
+func splitFrontMatter(content string, closers ...string) (block, body string) {
+	start := strings.IndexRune(content, '\n') + 1
+	for pos := start; pos < len(content); {
+		eol := strings.IndexRune(content[pos:], '\n')
+		if eol == -1 {
+			eol = len(content)
+		} else {
+			eol += pos
+		}
+		line := strings.TrimRight(content[pos:eol], " \t\r")
+		for _, c := range closers {
+			if line == c {
+				if eol < len(content) {
+					eol++
+				}
+				return content[start:pos], content[eol:]
+			}
+		}
+		pos = eol + 1
+	}
+	return "", content
+}
+
+// splitJSONFrontMatter decodes the JSON object at the start of content and
+// returns it along with whatever follows it.
+func splitJSONFrontMatter(content string) (map[string]interface{}, string, error) {
+	r := strings.NewReader(content)
+	dec := json.NewDecoder(r)
+	meta := map[string]interface{}{}
+	if err := dec.Decode(&meta); err != nil {
+		return nil, "", err
+	}
+	buffered, err := ioutil.ReadAll(dec.Buffered())
+	if err != nil {
+		return nil, "", err
+	}
+	rest, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return meta, string(buffered) + string(rest), nil
+}
+
+func newFrontMatter(meta map[string]interface{}) *frontMatter {
+	fm := &frontMatter{
+		Title:   metaString(meta["title"]),
+		Slug:    metaString(meta["slug"]),
+		Font:    metaString(meta["font"]),
+		Date:    metaTime(meta["date"]),
+		Lastmod: metaTime(meta["lastmod"]),
+		RTL:     metaBool(meta["rtl"]),
+	}
+	if lang := metaString(meta["lang"]); lang != "" {
+		fm.Lang = &lang
+	}
+	if draft := metaBool(meta["draft"]); draft != nil {
+		fm.Draft = *draft
+	}
+	return fm
+}
+
+func metaString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+func metaBool(v interface{}) *bool {
+	var b bool
+	switch t := v.(type) {
+	case bool:
+		b = t
+	case string:
+		switch strings.ToLower(strings.TrimSpace(t)) {
+		case "true", "yes", "on", "1":
+			b = true
+		case "false", "no", "off", "0":
+			b = false
+		default:
+			return nil
+		}
+	default:
+		return nil
+	}
+	return &b
+}
+
+func metaTime(v interface{}) *time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return &t
+	case string:
+		s := strings.TrimSpace(t)
+		for _, layout := range frontMatterDateLayouts {
+			if parsed, err := time.Parse(layout, s); err == nil {
+				return &parsed
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractFrontMatter(t *testing.T) {
+	date := time.Date(2019, 3, 10, 12, 30, 0, 0, time.UTC)
+	tt := []struct {
+		Name    string
+		Content string
+		Title   string
+		Slug    string
+		Body    string
+		Lang    string
+		RTL     bool
+		Draft   bool
+		Date    *time.Time
+	}{
+		{
+			Name:    "yaml",
+			Content: "---\ntitle: A Post\nslug: a-post\ndate: 2019-03-10T12:30:00Z\nlang: en\ndraft: true\n---\n\nThe body.",
+			Title:   "A Post",
+			Slug:    "a-post",
+			Body:    "The body.",
+			Lang:    "en",
+			Draft:   true,
+			Date:    &date,
+		}, {
+			Name:    "yaml jekyll date",
+			Content: "---\ntitle: \"Jekyll\"\ndate: 2019-03-10 12:30:00 +0000\n---\nThe body.",
+			Title:   "Jekyll",
+			Body:    "The body.",
+			Date:    &date,
+		}, {
+			Name:    "toml",
+			Content: "+++\ntitle = \"A Post\"\ndate = 2019-03-10T12:30:00Z\nrtl = true\n+++\nThe body.",
+			Title:   "A Post",
+			Body:    "The body.",
+			RTL:     true,
+			Date:    &date,
+		}, {
+			Name:    "json",
+			Content: "{\n\"title\": \"A Post\",\n\"slug\": \"a-post\"\n}\n\nThe body.",
+			Title:   "A Post",
+			Slug:    "a-post",
+			Body:    "The body.",
+		}, {
+			Name:    "crlf",
+			Content: "---\r\ntitle: A Post\r\n---\r\nThe body.",
+			Title:   "A Post",
+			Body:    "The body.",
+		}, {
+			Name:    "unterminated",
+			Content: "---\nnot front matter",
+			Body:    "---\nnot front matter",
+		}, {
+			Name:    "thematic breaks",
+			Content: "---\nSome text, not front matter.\n\n---\nMore text.",
+			Body:    "---\nSome text, not front matter.\n\n---\nMore text.",
+		}, {
+			Name:    "thematic breaks around a list",
+			Content: "---\n- one\n- two\n---\nMore text.",
+			Body:    "---\n- one\n- two\n---\nMore text.",
+		}, {
+			Name:    "empty yaml",
+			Content: "---\n---\nThe body.",
+			Body:    "The body.",
+		}, {
+			Name:    "json only",
+			Content: "{\"title\": \"Data\", \"count\": 2}\n",
+			Body:    "{\"title\": \"Data\", \"count\": 2}\n",
+		}, {
+			Name:    "brace without json",
+			Content: "{not json} but text",
+			Body:    "{not json} but text",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			fm, body, err := extractFrontMatter(tc.Content)
+			if err != nil {
+				t.Fatalf("failed to extract front matter: %v", err)
+			}
+			if body != tc.Body {
+				t.Fatalf("got body %q but expected %q", body, tc.Body)
+			}
+			if fm == nil {
+				if tc.Title != "" {
+					t.Fatal("front matter was nil")
+				}
+				return
+			}
+			if fm.Title != tc.Title {
+				t.Fatalf("got title %q but expected %q", fm.Title, tc.Title)
+			}
+			if fm.Slug != tc.Slug {
+				t.Fatalf("got slug %q but expected %q", fm.Slug, tc.Slug)
+			}
+			if tc.Lang != "" && (fm.Lang == nil || *fm.Lang != tc.Lang) {
+				t.Fatalf("got lang %v but expected %q", fm.Lang, tc.Lang)
+			}
+			if tc.RTL && (fm.RTL == nil || !*fm.RTL) {
+				t.Fatal("expected rtl to be set")
+			}
+			if fm.Draft != tc.Draft {
+				t.Fatalf("got draft %t but expected %t", fm.Draft, tc.Draft)
+			}
+			if tc.Date != nil && (fm.Date == nil || !fm.Date.Equal(*tc.Date)) {
+				t.Fatalf("got date %v but expected %v", fm.Date, tc.Date)
+			}
+		})
+	}
+}
+
+func TestExtractFrontMatterInvalid(t *testing.T) {
+	_, _, err := extractFrontMatter("---\ntitle: [unclosed\n---\nbody")
+	if err == nil {
+		t.Fatal("error was nil but front matter is invalid")
+	}
+}
+
+func TestFromBytesFrontMatter(t *testing.T) {
+	p, err := fromBytes([]byte("---\ntitle: Front Matter\nfont: sans\n---\n# Heading\n\nBody text."))
+	if err != nil {
+		t.Fatalf("failed to parse bytes: %v", err)
+	}
+	if p.Title != "Front Matter" {
+		t.Fatalf("got title %q but expected %q", p.Title, "Front Matter")
+	}
+	if p.Font != "sans" {
+		t.Fatalf("got font %q but expected %q", p.Font, "sans")
+	}
+	if p.Content != "# Heading\n\nBody text." {
+		t.Fatalf("front matter was not stripped from content: %q", p.Content)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+// FromFS reads all text and markdown files under root in fsys, including
+// those in subdirectories, and returns the parsed posts and an error if any.
+// Any fs.FS can be read, such as os.DirFS, a *zip.Reader or an embed.FS.
+//
+// The returned map is keyed by collection, taken from the name of the first
+// level directory under root a file is in. Files directly under root and
+// those marked as drafts in their front matter are included under DraftsKey.
+// Posts are given the ID and, unless their front matter sets one, the slug
+// in their file name, as in my-post_839ruu389ru9.txt. Hidden files and
+// directories, those starting with a dot, are skipped.
+func FromFS(fsys fs.FS, root string, opts ...Option) (ZipCollections, error) {
+	return fromFS(fsys, root, newOptions(opts))
+}
+
+// FromFSContext works as FromFS, checking ctx between files. Once ctx is done
+// the collections read until then are returned along with ctx.Err().
+func FromFSContext(ctx context.Context, fsys fs.FS, root string, opts ...Option) (ZipCollections, error) {
+	return fromFS(fsys, root, contextOptions(ctx, opts))
+}
+
+func fromFS(fsys fs.FS, root string, o *options) (ZipCollections, error) {
+	out := make(ZipCollections)
+	postErrors, err := walkFS(fsys, root, locatePathParts(root), o, out.add)
+	if canceled(o.ctx, err) {
+		return out, err
+	} else if err != nil {
+		return nil, err
+	}
+	return out, postErrors
+}
+
+// WalkFS calls fn for every post parsed from the files under root in fsys,
+// as FromFS would include them, without keeping them in memory. Files that
+// fail to parse are handled according to the ErrorPolicy, by default they do
+// not stop the walk and their errors are returned once it is done.
+func WalkFS(fsys fs.FS, root string, fn WalkFunc, opts ...Option) error {
+	postErrors, err := walkFS(fsys, root, locatePathParts(root), newOptions(opts), visitPosts(fn))
+	if err != nil {
+		return err
+	}
+	return postErrors
+}
+
+// fsLocateFunc places the file name read by walkFS, returning the collection
+// its directory stands for and the fsResolveFunc finishing the post parsed
+// from it, or a nil fsResolveFunc to leave the file out.
+type fsLocateFunc func(name string) (coll string, resolve fsResolveFunc)
+
+// fsResolveFunc finishes the post p parsed from a file with the front matter
+// fm, returning its key in a ZipCollections map.
+type fsResolveFunc func(p *writeas.PostParams, fm *frontMatter) string
+
+// locatePathParts is the fsLocateFunc of FromFS, placing the files under root
+// as applyPathParts does.
+func locatePathParts(root string) fsLocateFunc {
+	return func(name string) (string, fsResolveFunc) {
+		rel := relPath(root, name)
+		return pathColl(rel), func(p *writeas.PostParams, fm *frontMatter) string {
+			return applyPathParts(rel, p, fm)
+		}
+	}
+}
+
+// fsFile is a file found by walkFS along with where it was placed.
+type fsFile struct {
+	name    string
+	coll    string
+	resolve fsResolveFunc
+}
+
+// walkFS visits the posts under root in fsys, placed by locate. The files are
+// listed first and then parsed on the worker pool, in the order they were
+// found. Errors parsing single files are gathered into postErrors while err
+// is set if the walk was aborted.
+func walkFS(fsys fs.FS, root string, locate fsLocateFunc, o *options, visit visitFunc) (postErrors, err error) {
+	pattern := o.pattern
+	if pattern == "" {
+		pattern = "."
+	}
+	rx, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := visit(DraftsKey, nil); err != nil {
+		return nil, walkResult(err)
+	}
+	var files []fsFile
+	err = fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := o.ctx.Err(); err != nil {
+			return err
+		}
+		if name != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !rx.MatchString(d.Name()) {
+			return nil
+		}
+		if coll, resolve := locate(name); resolve != nil {
+			files = append(files, fsFile{name, coll, resolve})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type parsed struct {
+		fm  *frontMatter
+		enc string
+	}
+	info := make([]parsed, len(files))
+	err = parseOrdered(o.ctx, o.workers, len(files), func(i int) (post *writeas.PostParams, err error) {
+		post, info[i].fm, info[i].enc, err = fromFSFile(fsys, files[i].name)
+		return post, err
+	}, func(i int, post *writeas.PostParams, err error) error {
+		f := files[i]
+		if f.coll != DraftsKey {
+			if err := visit(f.coll, nil); err != nil {
+				return err
+			}
+		}
+		o.record(f.name, info[i].enc, post, err)
+		if skipped(err) {
+			return nil
+		} else if err != nil {
+			return o.fileFailed(&postErrors, fileError(f.name, "", err))
+		}
+		o.applyTitle(f.name, post)
+		return visit(f.resolve(post, info[i].fm), post)
+	})
+	return postErrors, walkResult(err)
+}
+
+// fromFSFile reads the file name from fsys and parses it into a post, as
+// parsePost does. The file's modification time is used when no date was
+// given in the content.
+func fromFSFile(fsys fs.FS, name string) (*writeas.PostParams, *frontMatter, string, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return parsePost(name, b, info.ModTime())
+}
+
+// relPath returns name relative to root, both being slash separated fs.FS
+// paths with name under root.
+func relPath(root, name string) string {
+	if root == "." {
+		return name
+	}
+	return strings.TrimPrefix(name, path.Clean(root)+"/")
+}
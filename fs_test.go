@@ -0,0 +1,129 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/zip"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFromFS(t *testing.T) {
+	modTime := time.Date(2019, 3, 10, 12, 30, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"site/top.md":            {Data: []byte("# Top\n\ndraft post"), ModTime: modTime},
+		"site/blog/post.md":      {Data: []byte("---\ntitle: Front\nslug: front\n---\nbody"), ModTime: modTime},
+		"site/blog/2019/year.md": {Data: []byte("# Nested\n\nyear post"), ModTime: modTime},
+		"site/blog/image.png":    {Data: []byte("\x89PNG\r\n\x1a\n"), ModTime: modTime},
+		"other/skipped.md":       {Data: []byte("outside of root"), ModTime: modTime},
+	}
+
+	colls, err := FromFS(fsys, "site")
+	if err != nil {
+		t.Fatalf("failed to parse fs: %v", err)
+	}
+	if len(colls[DraftsKey]) != 1 {
+		t.Fatalf("draft count mismatch: got %d, expecting 1", len(colls[DraftsKey]))
+	}
+	if p := colls[DraftsKey][0]; p.Title != "Top" || p.Created == nil || !p.Created.Equal(modTime) {
+		t.Fatalf("got title %q and created %v", p.Title, p.Created)
+	}
+	if len(colls["blog"]) != 2 {
+		t.Fatalf("blog count mismatch: got %d, expecting 2", len(colls["blog"]))
+	}
+	if len(colls) != 2 {
+		t.Fatalf("collection count mismatch: got %d, expecting 2", len(colls))
+	}
+}
+
+func TestFromFSZip(t *testing.T) {
+	a := getTestZip(t, filesWDirs)
+	r, err := zip.OpenReader(a)
+	if err != nil {
+		t.Fatalf("opening test zip: %v", err)
+	}
+	defer r.Close()
+
+	colls, err := FromFS(r, ".")
+	if err != nil {
+		t.Fatalf("failed to parse zip as fs: %v", err)
+	}
+	expected, err := FromZipDirs(a)
+	if err != nil {
+		t.Fatalf("failed to parse zip: %v", err)
+	}
+	for coll, posts := range expected {
+		if len(colls[coll]) != len(posts) {
+			t.Fatalf("%s count mismatch: got %d, expecting %d", coll, len(colls[coll]), len(posts))
+		}
+	}
+}
+
+func TestFromFSArchiveParity(t *testing.T) {
+	entries := fileList{
+		{"top_xyz.txt", "top post"},
+		{"blog/2019/my-post_abc123.txt", "nested post"},
+		{"blog/other_def456.md", "---\nslug: front\n---\nbody"},
+		{"notes/draft.md", "---\ndraft: true\n---\nnot yet"},
+	}
+	type result struct {
+		Coll, Collection, ID, Slug string
+	}
+	flatten := func(colls ZipCollections) map[string]result {
+		out := map[string]result{}
+		for coll, posts := range colls {
+			for _, p := range posts {
+				out[p.ID] = result{coll, p.Collection, p.ID, p.Slug}
+			}
+		}
+		return out
+	}
+	expected := map[string]result{
+		"xyz":    {DraftsKey, "", "xyz", "top"},
+		"abc123": {"blog", "blog", "abc123", "my-post"},
+		"def456": {"blog", "blog", "def456", "front"},
+		"draft":  {DraftsKey, "", "draft", ""},
+	}
+
+	a := getTestZip(t, entries)
+	zipColls, err := FromZipDirs(a)
+	if err != nil {
+		t.Fatalf("failed to parse zip: %v", err)
+	}
+	r, err := zip.OpenReader(a)
+	if err != nil {
+		t.Fatalf("opening test zip: %v", err)
+	}
+	defer r.Close()
+	fsColls, err := FromFS(r, ".")
+	if err != nil {
+		t.Fatalf("failed to parse zip as fs: %v", err)
+	}
+	tarArchive := getTestTar(t, entries, ".tar", compressions[0].Writer)
+	defer os.Remove(tarArchive)
+	tarColls, err := FromTarDirs(tarArchive)
+	if err != nil {
+		t.Fatalf("failed to parse tar: %v", err)
+	}
+
+	for name, colls := range map[string]ZipCollections{"zip": zipColls, "fs": fsColls, "tar": tarColls} {
+		if _, ok := colls["notes"]; !ok {
+			t.Fatalf("%s: got collections %v but expected notes among them", name, colls)
+		}
+		got := flatten(colls)
+		if len(got) != len(expected) {
+			t.Fatalf("%s: got %d posts but expected %d", name, len(got), len(expected))
+		}
+		for id, e := range expected {
+			if got[id] != e {
+				t.Fatalf("%s: got %+v but expected %+v", name, got[id], e)
+			}
+		}
+	}
+}
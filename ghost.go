@@ -0,0 +1,349 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+// GhostKey is the key for published Ghost posts in a ZipCollections map
+// when their author could not be found in the export.
+const GhostKey = "ghost"
+
+// ghostExport is the subset of a Ghost admin export needed to build posts.
+// Newer exports wrap the data in a db array, older ones do not.
+type ghostExport struct {
+	DB   []ghostDB `json:"db"`
+	Data ghostData `json:"data"`
+}
+
+type ghostDB struct {
+	Data ghostData `json:"data"`
+}
+
+type ghostData struct {
+	Posts        []ghostPost `json:"posts"`
+	Tags         []ghostTag  `json:"tags"`
+	PostsTags    []ghostRel  `json:"posts_tags"`
+	Users        []ghostUser `json:"users"`
+	PostsAuthors []ghostRel  `json:"posts_authors"`
+}
+
+type ghostPost struct {
+	ID          ghostID   `json:"id"`
+	Title       string    `json:"title"`
+	Slug        string    `json:"slug"`
+	Mobiledoc   string    `json:"mobiledoc"`
+	Lexical     string    `json:"lexical"`
+	Markdown    string    `json:"markdown"`
+	HTML        string    `json:"html"`
+	Type        string    `json:"type"`
+	Page        ghostBool `json:"page"`
+	Status      string    `json:"status"`
+	AuthorID    ghostID   `json:"author_id"`
+	CreatedAt   ghostTime `json:"created_at"`
+	UpdatedAt   ghostTime `json:"updated_at"`
+	PublishedAt ghostTime `json:"published_at"`
+}
+
+type ghostTag struct {
+	ID         ghostID `json:"id"`
+	Name       string  `json:"name"`
+	Visibility string  `json:"visibility"`
+}
+
+type ghostUser struct {
+	ID   ghostID `json:"id"`
+	Slug string  `json:"slug"`
+}
+
+// ghostRel is a row of the posts_tags or posts_authors join tables.
+type ghostRel struct {
+	PostID    ghostID `json:"post_id"`
+	TagID     ghostID `json:"tag_id"`
+	AuthorID  ghostID `json:"author_id"`
+	SortOrder int     `json:"sort_order"`
+}
+
+// ghostID holds an ID that is a number in older Ghost exports and an
+// ObjectID string in newer ones.
+type ghostID string
+
+func (id *ghostID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*id = ghostID(s)
+		return nil
+	}
+	*id = ghostID(strings.Trim(string(b), `"`))
+	return nil
+}
+
+// ghostBool holds a boolean that older Ghost exports store as 0 or 1.
+type ghostBool bool
+
+func (gb *ghostBool) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	*gb = ghostBool(s == "true" || s == "1")
+	return nil
+}
+
+// ghostTime holds a timestamp that is either an ISO 8601 string or, in older
+// exports, milliseconds since the epoch.
+type ghostTime struct {
+	Time *time.Time
+}
+
+func (gt *ghostTime) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		t := time.Unix(0, ms*int64(time.Millisecond)).UTC()
+		gt.Time = &t
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, str); err == nil {
+			gt.Time = &t
+			return nil
+		}
+	}
+	return nil
+}
+
+// FromGhost reads a Ghost JSON export from path and returns the posts it
+// contains and an error if any.
+//
+// Published posts are keyed by the slug of their primary author, or by the
+// collection given with WithCollection, draft and scheduled posts are
+// included under DraftsKey. Pages are not included.
+func FromGhost(path string, opts ...Option) (ZipCollections, error) {
+	return fromGhost(path, newOptions(opts))
+}
+
+// FromGhostContext works as FromGhost, checking ctx between posts and while
+// converting them. Once ctx is done the posts read until then are returned
+// along with ctx.Err().
+func FromGhostContext(ctx context.Context, path string, opts ...Option) (ZipCollections, error) {
+	return fromGhost(path, contextOptions(ctx, opts))
+}
+
+func fromGhost(path string, o *options) (ZipCollections, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	export := ghostExport{}
+	if err := json.NewDecoder(f).Decode(&export); err != nil {
+		return nil, fileError(path, "", err)
+	}
+	data := export.Data
+	if len(export.DB) > 0 {
+		data = export.DB[0].Data
+	}
+
+	colls, postErrors, err := data.collections(path, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return colls, err
+}
+
+// collections builds the post collections from the export data, keyed by
+// the collection of o if set and by author slug otherwise. Errors converting
+// single posts of the export at path are gathered into postErrors, as the
+// error policy allows, while err is set if the import was aborted.
+func (d ghostData) collections(path string, o *options) (out ZipCollections, postErrors, err error) {
+	tags := map[ghostID]ghostTag{}
+	for _, t := range d.Tags {
+		tags[t.ID] = t
+	}
+	users := map[ghostID]string{}
+	for _, u := range d.Users {
+		users[u.ID] = u.Slug
+	}
+	postTags := map[ghostID][]ghostRel{}
+	for _, r := range d.PostsTags {
+		postTags[r.PostID] = append(postTags[r.PostID], r)
+	}
+	postAuthors := map[ghostID]ghostRel{}
+	for _, r := range d.PostsAuthors {
+		if cur, ok := postAuthors[r.PostID]; !ok || r.SortOrder < cur.SortOrder {
+			postAuthors[r.PostID] = r
+		}
+	}
+
+	out = ZipCollections{DraftsKey: []*writeas.PostParams{}}
+	for _, gp := range d.Posts {
+		if err := o.ctx.Err(); err != nil {
+			return out, postErrors, err
+		}
+		if gp.Type == "page" || bool(gp.Page) {
+			continue
+		}
+		body, err := gp.content(o.ctx)
+		if canceled(o.ctx, err) {
+			return out, postErrors, err
+		} else if err != nil {
+			if err := o.postFailed(&postErrors, path, gp.Slug, err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		rels := postTags[gp.ID]
+		sort.SliceStable(rels, func(i, j int) bool {
+			return rels[i].SortOrder < rels[j].SortOrder
+		})
+		var names []string
+		for _, r := range rels {
+			if t, ok := tags[r.TagID]; ok && t.Visibility != "internal" && !strings.HasPrefix(t.Name, "#") {
+				names = append(names, t.Name)
+			}
+		}
+		if ht := hashtags(names); ht != "" {
+			body = strings.TrimSpace(body + "\n\n" + ht)
+		}
+		if body == "" {
+			continue
+		}
+
+		p := &writeas.PostParams{
+			Title:   gp.Title,
+			Slug:    gp.Slug,
+			Content: body,
+			Created: gp.PublishedAt.Time,
+			Updated: gp.UpdatedAt.Time,
+		}
+		if p.Created == nil {
+			p.Created = gp.CreatedAt.Time
+		}
+
+		key := DraftsKey
+		if gp.Status == "published" {
+			authorID := gp.AuthorID
+			if r, ok := postAuthors[gp.ID]; ok {
+				authorID = r.AuthorID
+			}
+			key = users[authorID]
+			if key == "" {
+				key = GhostKey
+			}
+			key = o.collectionKey(key)
+			p.Collection = key
+		}
+		out[key] = append(out[key], p)
+	}
+	return out, postErrors, nil
+}
+
+// content returns the post body as Markdown, preferring the markdown cards
+// from mobiledoc or lexical when the post is made up of them alone, then the
+// markdown source older exports keep alongside the HTML, and converting the
+// rendered HTML otherwise.
+func (gp ghostPost) content(ctx context.Context) (string, error) {
+	if md, ok := mobiledocMarkdown(gp.Mobiledoc); ok {
+		return md, nil
+	}
+	if md, ok := lexicalMarkdown(gp.Lexical); ok {
+		return md, nil
+	}
+	if md := strings.TrimSpace(gp.Markdown); md != "" {
+		return md, nil
+	}
+	return htmlStringToMarkdown(ctx, gp.HTML)
+}
+
+// mobiledocMarkdown returns the text of the markdown cards in a mobiledoc
+// document if every section of it is one.
+func mobiledocMarkdown(doc string) (string, bool) {
+	if doc == "" {
+		return "", false
+	}
+	var md struct {
+		Cards    [][]json.RawMessage `json:"cards"`
+		Sections [][]json.RawMessage `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(doc), &md); err != nil {
+		return "", false
+	}
+
+	var parts []string
+	for _, section := range md.Sections {
+		var kind, index int
+		if len(section) < 2 || json.Unmarshal(section[0], &kind) != nil || kind != 10 {
+			// 10 is a card section, anything else is content outside a card
+			return "", false
+		}
+		if json.Unmarshal(section[1], &index) != nil || index >= len(md.Cards) || len(md.Cards[index]) < 2 {
+			return "", false
+		}
+		var name string
+		var payload struct {
+			Markdown string `json:"markdown"`
+		}
+		json.Unmarshal(md.Cards[index][0], &name)
+		json.Unmarshal(md.Cards[index][1], &payload)
+		if name != "markdown" && name != "card-markdown" {
+			return "", false
+		}
+		parts = append(parts, strings.TrimSpace(payload.Markdown))
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, "\n\n"), true
+}
+
+// lexicalMarkdown returns the text of the markdown cards in a lexical
+// document if every top level node of it is one.
+func lexicalMarkdown(doc string) (string, bool) {
+	if doc == "" {
+		return "", false
+	}
+	var lex struct {
+		Root struct {
+			Children []struct {
+				Type     string `json:"type"`
+				Markdown string `json:"markdown"`
+			} `json:"children"`
+		} `json:"root"`
+	}
+	if err := json.Unmarshal([]byte(doc), &lex); err != nil {
+		return "", false
+	}
+
+	var parts []string
+	for _, n := range lex.Root.Children {
+		if n.Type != "markdown" {
+			return "", false
+		}
+		parts = append(parts, strings.TrimSpace(n.Markdown))
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, "\n\n"), true
+}
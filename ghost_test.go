@@ -0,0 +1,79 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const ghostExportJSON = `{"db": [{"meta": {"version": "3.0.0"}, "data": {
+	"posts": [
+		{"id": "1", "title": "Markdown Post", "slug": "markdown-post", "type": "post", "status": "published",
+			"mobiledoc": "{\"version\":\"0.3.1\",\"cards\":[[\"markdown\",{\"markdown\":\"Some *markdown*.\"}]],\"sections\":[[10,0]]}",
+			"html": "<p>Some <em>markdown</em>.</p>",
+			"published_at": "2019-03-10T12:30:00.000Z", "updated_at": "2019-03-11T12:30:00.000Z"},
+		{"id": "2", "title": "HTML Post", "slug": "html-post", "type": "post", "status": "published",
+			"mobiledoc": "{\"version\":\"0.3.1\",\"cards\":[],\"sections\":[[1,\"p\",[[0,[],0,\"Rich text\"]]]]}",
+			"html": "<p>Rich <strong>text</strong></p>", "published_at": "2019-04-10T12:30:00.000Z"},
+		{"id": "5", "title": "Old Post", "slug": "old-post", "type": "post", "status": "published",
+			"markdown": "Old _style_ post", "html": "<p>Old <em>style</em> post</p>", "published_at": "2015-04-10 12:30:00"},
+		{"id": "3", "title": "Draft", "slug": "draft", "type": "post", "status": "draft", "html": "<p>Later</p>"},
+		{"id": "4", "title": "About", "slug": "about", "type": "page", "status": "published", "html": "<p>Page</p>"}
+	],
+	"tags": [{"id": "t1", "name": "Getting Started"}, {"id": "t2", "name": "#internal"}],
+	"posts_tags": [{"post_id": "1", "tag_id": "t1", "sort_order": 0}, {"post_id": "1", "tag_id": "t2", "sort_order": 1}],
+	"users": [{"id": "u1", "slug": "matt"}],
+	"posts_authors": [{"post_id": "1", "author_id": "u1"}, {"post_id": "2", "author_id": "u1"}, {"post_id": "5", "author_id": "u1"}]
+}}]}`
+
+func TestFromGhost(t *testing.T) {
+	f, err := ioutil.TempFile("", "ghost-*.json")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(ghostExportJSON)
+	if err != nil {
+		t.Fatalf("writing temp file: %v", err)
+	}
+	f.Close()
+
+	colls, err := FromGhost(f.Name())
+	if err != nil {
+		t.Fatalf("failed to parse ghost export: %v", err)
+	}
+	if len(colls[DraftsKey]) != 1 {
+		t.Fatalf("draft count mismatch: got %d, expecting 1", len(colls[DraftsKey]))
+	}
+	if len(colls["matt"]) != 3 {
+		t.Fatalf("post count mismatch: got %d, expecting 3", len(colls["matt"]))
+	}
+
+	md := colls["matt"][0]
+	if md.Content != "Some *markdown*.\n\n#GettingStarted" {
+		t.Fatalf("got content %q", md.Content)
+	}
+	if md.Created == nil || md.Updated == nil {
+		t.Fatal("expected created and updated dates to be set")
+	}
+	if colls["matt"][1].Content != "Rich **text**" {
+		t.Fatalf("got content %q", colls["matt"][1].Content)
+	}
+	if colls["matt"][2].Content != "Old _style_ post" {
+		t.Fatalf("got content %q", colls["matt"][2].Content)
+	}
+
+	colls, err = FromGhost(f.Name(), WithCollection("blog"))
+	if err != nil {
+		t.Fatalf("failed to parse ghost export: %v", err)
+	}
+	if len(colls["blog"]) != 3 {
+		t.Fatalf("post count mismatch: got %d, expecting 3", len(colls["blog"]))
+	}
+}
@@ -1,9 +1,19 @@
 module github.com/writeas/import
 
-go 1.12
+go 1.17
 
 require (
-	github.com/hashicorp/go-multierror v1.0.0
-	github.com/writeas/go-writeas v1.1.0
+	github.com/BurntSushi/toml v0.3.0
+	github.com/hashicorp/go-multierror v1.1.1
+	github.com/klauspost/compress v1.15.15
 	github.com/writeas/go-writeas/v2 v2.0.2
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	code.as/core/socks v1.0.0 // indirect
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/writeas/impart v1.1.0 // indirect
 )
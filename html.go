@@ -0,0 +1,108 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"strings"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func init() {
+	for _, key := range []string{".html", ".htm", ".xhtml", "text/html"} {
+		registerParser(key, parseHTML)
+	}
+}
+
+// parseHTML is the built-in parser for HTML files. The body is converted to
+// Markdown, with the title taken from the <title> element or else the first
+// <h1>. The language and direction of the post come from the lang and dir
+// attributes of the document, and the creation date from a date meta tag or
+// else the first <time> element.
+func parseHTML(name string, b []byte) (*writeas.PostParams, *frontMatter, string, error) {
+	text, enc, err := decodeText(b)
+	if err != nil {
+		return nil, nil, enc, err
+	}
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil, nil, enc, err
+	}
+
+	p := &writeas.PostParams{}
+	h1 := findNode(doc, atomMatch(atom.H1))
+	if n := findNode(doc, atomMatch(atom.Title)); n != nil {
+		p.Title = singleLine(textContent(n))
+	}
+	if p.Title == "" && h1 != nil {
+		p.Title = singleLine(textContent(h1))
+	}
+
+	body := findNode(doc, atomMatch(atom.Body))
+	if body == nil {
+		body = doc
+	}
+	p.Content = htmlToMarkdown(body, func(n *html.Node) bool {
+		// the heading repeating the title is not part of the content
+		return n == h1 && singleLine(textContent(n)) == p.Title
+	})
+	if p.Content == "" {
+		return nil, nil, enc, ErrEmptyFile
+	}
+
+	if root := findNode(doc, atomMatch(atom.Html)); root != nil {
+		if lang := strings.TrimSpace(attr(root, "lang")); lang != "" {
+			p.Language = &lang
+		}
+	}
+	p.IsRTL = htmlRTL(doc, body)
+	p.Created = htmlDate(doc)
+
+	return p, nil, enc, nil
+}
+
+// htmlRTL returns whether the document is written right to left, going by
+// the dir attribute of its <html> or <body> element, or nil if neither has
+// one.
+func htmlRTL(doc, body *html.Node) *bool {
+	for _, n := range []*html.Node{findNode(doc, atomMatch(atom.Html)), body} {
+		if n == nil {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(attr(n, "dir"))) {
+		case "rtl":
+			rtl := true
+			return &rtl
+		case "ltr":
+			rtl := false
+			return &rtl
+		}
+	}
+	return nil
+}
+
+// htmlDate returns the date of the document from a <meta name="date"> tag or
+// else the datetime attribute of the first <time> element, in any of the
+// formats accepted in front matter.
+func htmlDate(doc *html.Node) *time.Time {
+	meta := findNode(doc, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.DataAtom == atom.Meta &&
+			strings.EqualFold(attr(n, "name"), "date")
+	})
+	if meta != nil {
+		if t := metaTime(attr(meta, "content")); t != nil {
+			return t
+		}
+	}
+	if n := findNode(doc, atomMatch(atom.Time)); n != nil {
+		return metaTime(attr(n, "datetime"))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHTML(t *testing.T) {
+	tt := []struct {
+		Name     string
+		HTML     string
+		Title    string
+		Content  string
+		Language string
+		RTL      *bool
+		Created  string
+		Error    error
+	}{
+		{
+			Name: "document",
+			HTML: `<!DOCTYPE html>
+<html lang="fr">
+<head>
+<title>Bonjour</title>
+<meta name="date" content="2019-05-04">
+</head>
+<body>
+<h1>Bonjour</h1>
+<p>Un <em>petit</em> texte.</p>
+</body>
+</html>`,
+			Title:    "Bonjour",
+			Content:  "Un *petit* texte.",
+			Language: "fr",
+			Created:  "2019-05-04T00:00:00Z",
+		}, {
+			Name:    "heading title",
+			HTML:    `<h1>My Post</h1><p>First.</p><h2>Part</h2><p>Second.</p>`,
+			Title:   "My Post",
+			Content: "First.\n\n## Part\n\nSecond.",
+		}, {
+			Name:    "title and different heading",
+			HTML:    `<html><head><title>My Post | Blog</title></head><body><h1>My Post</h1><p>Text.</p></body></html>`,
+			Title:   "My Post | Blog",
+			Content: "# My Post\n\nText.",
+		}, {
+			Name:     "rtl",
+			HTML:     `<html lang="ar" dir="RTL"><body><p>نص</p></body></html>`,
+			Content:  "نص",
+			RTL:      &[]bool{true}[0],
+			Language: "ar",
+		}, {
+			Name:    "time",
+			HTML:    `<article><time datetime="2020-01-02T03:04:05Z">January 2</time><p>Text.</p></article>`,
+			Content: "January 2\n\nText.",
+			Created: "2020-01-02T03:04:05Z",
+		}, {
+			Name:  "empty body",
+			HTML:  `<html><head><title>Nothing</title></head><body></body></html>`,
+			Error: ErrEmptyFile,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, _, _, err := parseFile("post.html", []byte(tc.HTML))
+			if err != tc.Error {
+				t.Fatalf("got error %v but expected %v", err, tc.Error)
+			}
+			if err != nil {
+				return
+			}
+			if p.Title != tc.Title {
+				t.Fatalf("got title %q but expected %q", p.Title, tc.Title)
+			}
+			if p.Content != tc.Content {
+				t.Fatalf("got content %q but expected %q", p.Content, tc.Content)
+			}
+			var lang string
+			if p.Language != nil {
+				lang = *p.Language
+			}
+			if lang != tc.Language {
+				t.Fatalf("got language %q but expected %q", lang, tc.Language)
+			}
+			if (p.IsRTL == nil) != (tc.RTL == nil) || (p.IsRTL != nil && *p.IsRTL != *tc.RTL) {
+				t.Fatalf("got rtl %v but expected %v", p.IsRTL, tc.RTL)
+			}
+			var created string
+			if p.Created != nil {
+				created = p.Created.Format(time.RFC3339)
+			}
+			if created != tc.Created {
+				t.Fatalf("got created %q but expected %q", created, tc.Created)
+			}
+		})
+	}
+}
+
+func TestParseHTMLSniffed(t *testing.T) {
+	p, _, _, err := parseFile("export", []byte("<!DOCTYPE html><html><body><p>Hello <strong>there</strong>.</p></body></html>"))
+	if err != nil {
+		t.Fatalf("failed to parse post: %v", err)
+	}
+	if p.Content != "Hello **there**." {
+		t.Fatalf("got content %q but expected %q", p.Content, "Hello **there**.")
+	}
+}
@@ -0,0 +1,566 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	whitespaceRx = regexp.MustCompile(`\s+`)
+	// lineBreakRx matches a hard line break followed by the collapsed
+	// whitespace of the next text node
+	lineBreakRx = regexp.MustCompile(`  \n[ \t]+`)
+	// blockMarkerRx and listNumberRx match text at the start of a line that
+	// Markdown would read as a heading, blockquote or list
+	blockMarkerRx = regexp.MustCompile(`(?m)^([ \t]*)([#>+-])`)
+	listNumberRx  = regexp.MustCompile(`(?m)^([ \t]*\d{1,9})([.)])`)
+)
+
+var (
+	// textEscaper escapes the characters of text nodes that Markdown would
+	// read as inline syntax, or as raw HTML and entities once decoded from
+	// the document.
+	textEscaper = strings.NewReplacer(
+		`&`, `&amp;`,
+		`<`, `&lt;`,
+		`\`, `\\`,
+		"`", "\\`",
+		`*`, `\*`,
+		`_`, `\_`,
+		`[`, `\[`,
+		`]`, `\]`,
+	)
+	// urlEscaper escapes link destinations so that they end at the closing
+	// parenthesis of the link.
+	urlEscaper = strings.NewReplacer(
+		`(`, `\(`,
+		`)`, `\)`,
+		` `, `%20`,
+	)
+)
+
+// blockElements are rendered as separate Markdown blocks, everything else is
+// treated as inline content.
+var blockElements = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Article:    true,
+	atom.Aside:      true,
+	atom.Blockquote: true,
+	atom.Body:       true,
+	atom.Center:     true,
+	atom.Dd:         true,
+	atom.Details:    true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Fieldset:   true,
+	atom.Figcaption: true,
+	atom.Figure:     true,
+	atom.Footer:     true,
+	atom.Form:       true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Header:     true,
+	atom.Hr:         true,
+	atom.Html:       true,
+	atom.Li:         true,
+	atom.Main:       true,
+	atom.Nav:        true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Summary:    true,
+	atom.Table:      true,
+	atom.Ul:         true,
+}
+
+// skippedElements are never rendered.
+var skippedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Noscript: true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Template: true,
+	atom.Title:    true,
+}
+
+// htmlToMarkdown converts the children of n into Markdown. Elements for which
+// skip returns true are left out, skip may be nil.
+func htmlToMarkdown(n *html.Node, skip func(*html.Node) bool) string {
+	md, _ := htmlToMarkdownContext(context.Background(), n, skip)
+	return md
+}
+
+// htmlToMarkdownContext works as htmlToMarkdown, giving up on the conversion
+// and returning ctx.Err() once ctx is done.
+func htmlToMarkdownContext(ctx context.Context, n *html.Node, skip func(*html.Node) bool) (string, error) {
+	c := mdConverter{ctx: ctx, skip: skip}
+	md := strings.TrimSpace(c.blocks(n))
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return md, nil
+}
+
+// htmlStringToMarkdown parses the HTML document or fragment s and converts it
+// into Markdown, stopping once ctx is done.
+func htmlStringToMarkdown(ctx context.Context, s string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	return htmlToMarkdownContext(ctx, doc, nil)
+}
+
+// mdConverter renders a parsed HTML tree as Markdown.
+type mdConverter struct {
+	ctx  context.Context
+	skip func(*html.Node) bool
+}
+
+// done reports whether the conversion should stop, its result then being
+// discarded.
+func (c mdConverter) done() bool {
+	return c.ctx.Err() != nil
+}
+
+func (c mdConverter) skipped(n *html.Node) bool {
+	if n.Type == html.CommentNode || n.Type == html.DoctypeNode {
+		return true
+	}
+	if n.Type == html.ElementNode && skippedElements[n.DataAtom] {
+		return true
+	}
+	return c.skip != nil && c.skip(n)
+}
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blockElements[n.DataAtom]
+}
+
+// blocks renders the children of n as Markdown blocks separated by blank
+// lines. Runs of inline children are gathered into paragraphs.
+func (c mdConverter) blocks(n *html.Node) string {
+	var (
+		parts  []string
+		inline strings.Builder
+	)
+	flush := func() {
+		if s := strings.TrimSpace(lineBreakRx.ReplaceAllString(inline.String(), "  \n")); s != "" {
+			parts = append(parts, escapeLineStarts(s))
+		}
+		inline.Reset()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if c.done() {
+			return ""
+		}
+		if c.skipped(child) {
+			continue
+		}
+		if isBlock(child) {
+			flush()
+			if s := c.block(child); strings.TrimSpace(s) != "" {
+				parts = append(parts, s)
+			}
+			continue
+		}
+		inline.WriteString(c.inlineNode(child))
+	}
+	flush()
+	return strings.Join(parts, "\n\n")
+}
+
+// block renders a single block level element.
+func (c mdConverter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := singleLine(c.inline(n))
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", level) + " " + text
+	case atom.P, atom.Dt, atom.Dd, atom.Summary, atom.Address:
+		return escapeLineStarts(strings.TrimSpace(c.inline(n)))
+	case atom.Figcaption:
+		if text := strings.TrimSpace(c.inline(n)); text != "" {
+			return "*" + text + "*"
+		}
+		return ""
+	case atom.Hr:
+		return "---"
+	case atom.Pre:
+		return c.pre(n)
+	case atom.Blockquote:
+		return prefixLines(c.blocks(n), "> ")
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Li:
+		return c.listItem(n, "- ")
+	case atom.Table:
+		return c.table(n)
+	}
+	return c.blocks(n)
+}
+
+func (c mdConverter) pre(n *html.Node) string {
+	lang := ""
+	code := n
+	if n.FirstChild != nil && n.FirstChild == n.LastChild && n.FirstChild.DataAtom == atom.Code {
+		code = n.FirstChild
+		for _, class := range strings.Fields(attr(code, "class")) {
+			if strings.HasPrefix(class, "language-") {
+				lang = strings.TrimPrefix(class, "language-")
+				break
+			}
+		}
+	}
+	text := strings.Trim(c.preText(code), "\n")
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+// preText returns the text of n with line breaks kept intact.
+func (c mdConverter) preText(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case child.Type == html.TextNode:
+			b.WriteString(child.Data)
+		case child.DataAtom == atom.Br:
+			b.WriteString("\n")
+		case !c.skipped(child):
+			b.WriteString(c.preText(child))
+		}
+	}
+	return b.String()
+}
+
+func (c mdConverter) list(n *html.Node) string {
+	var items []string
+	num, _ := strconv.Atoi(attr(n, "start"))
+	if num == 0 {
+		num = 1
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if c.done() {
+			return ""
+		}
+		if c.skipped(child) || child.Type != html.ElementNode {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+		if child.DataAtom != atom.Li {
+			// stray content inside a list, render it as its own item
+			if s := strings.TrimSpace(c.block(child)); s != "" {
+				items = append(items, indentItem(s, marker))
+			}
+			continue
+		}
+		if s := c.listItem(child, marker); s != "" {
+			items = append(items, s)
+		}
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c mdConverter) listItem(n *html.Node, marker string) string {
+	s := strings.TrimSpace(c.blocks(n))
+	if s == "" {
+		return ""
+	}
+	return indentItem(s, marker)
+}
+
+func (c mdConverter) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil && !c.done(); child = child.NextSibling {
+			switch child.DataAtom {
+			case atom.Tr:
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+						row = append(row, strings.Replace(singleLine(c.inline(cell)), "|", `\|`, -1))
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", cols))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inline renders the children of n as inline Markdown.
+func (c mdConverter) inline(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(c.inlineNode(child))
+	}
+	return lineBreakRx.ReplaceAllString(b.String(), "  \n")
+}
+
+func (c mdConverter) inlineNode(n *html.Node) string {
+	if c.skipped(n) {
+		return ""
+	}
+	if n.Type == html.TextNode {
+		return textEscaper.Replace(whitespaceRx.ReplaceAllString(n.Data, " "))
+	}
+	if n.Type != html.ElementNode {
+		return c.inline(n)
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "  \n"
+	case atom.Strong, atom.B:
+		return wrapInline(c.inline(n), "**")
+	case atom.Em, atom.I, atom.Cite:
+		return wrapInline(c.inline(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return wrapInline(c.inline(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		text := textContent(n)
+		if text == "" {
+			return ""
+		}
+		fence := "`"
+		for strings.Contains(text, fence) {
+			fence += "`"
+		}
+		return fence + text + fence
+	case atom.A:
+		text := strings.TrimSpace(c.inline(n))
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		if text == "" {
+			return ""
+		}
+		return "[" + text + "](" + urlEscaper.Replace(href) + ")"
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		alt := textEscaper.Replace(singleLine(attr(n, "alt")))
+		return "![" + alt + "](" + urlEscaper.Replace(src) + ")"
+	case atom.Iframe, atom.Video, atom.Audio, atom.Embed:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		return "[" + textEscaper.Replace(src) + "](" + urlEscaper.Replace(src) + ")"
+	case atom.Input:
+		if !strings.EqualFold(attr(n, "type"), "checkbox") {
+			return ""
+		}
+		box := "[ ]"
+		if hasAttr(n, "checked") {
+			box = "[x]"
+		}
+		if next := n.NextSibling; next != nil && next.Type == html.TextNode && strings.TrimLeftFunc(next.Data, unicode.IsSpace) != next.Data {
+			return box
+		}
+		return box + " "
+	}
+	if isBlock(n) {
+		// block elements nested inside inline ones are flattened
+		return " " + c.inline(n) + " "
+	}
+	return c.inline(n)
+}
+
+// escapeLineStarts escapes the text at the start of each line of the
+// paragraph s that would otherwise begin a heading, blockquote or list.
+func escapeLineStarts(s string) string {
+	s = blockMarkerRx.ReplaceAllString(s, `$1\$2`)
+	return listNumberRx.ReplaceAllString(s, `$1\$2`)
+}
+
+// wrapInline surrounds s with the marker while keeping leading and trailing
+// whitespace outside of it, which Markdown requires.
+func wrapInline(s, marker string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:strings.Index(s, trimmed)]
+	trail := s[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+func singleLine(s string) string {
+	return strings.TrimSpace(whitespaceRx.ReplaceAllString(s, " "))
+}
+
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(prefix+l, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentItem adds the list marker to the first line of s and indents the
+// following lines to line up with it.
+func indentItem(s, marker string) string {
+	lines := strings.Split(s, "\n")
+	pad := strings.Repeat(" ", len(marker))
+	for i := range lines {
+		if i == 0 {
+			lines[i] = marker + lines[i]
+		} else if lines[i] != "" {
+			lines[i] = pad + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hashtags formats tags as space separated hashtags, removing the characters
+// that would end a hashtag early.
+func hashtags(tags []string) string {
+	var out []string
+	for _, t := range tags {
+		tag := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return r
+			}
+			return -1
+		}, strings.Title(t))
+		if tag != "" {
+			out = append(out, "#"+tag)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// attr returns the value of the attribute key on n, or an empty string.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether n has the attribute key, whatever its value.
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// hasClass reports whether n has class in its class attribute.
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// findNode returns the first node in the tree under n, including n, for
+// which match returns true, or nil.
+func findNode(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findNode(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// classMatch returns a findNode matcher for elements with class.
+func classMatch(class string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && hasClass(n, class)
+	}
+}
+
+// idMatch returns a findNode matcher for the element with id.
+func idMatch(id string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && attr(n, "id") == id
+	}
+}
+
+// atomMatch returns a findNode matcher for elements of type a.
+func atomMatch(a atom.Atom) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.DataAtom == a
+	}
+}
+
+// textContent returns the concatenated text of all text nodes under n.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
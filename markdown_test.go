@@ -0,0 +1,106 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tt := []struct {
+		Name     string
+		HTML     string
+		Markdown string
+	}{
+		{
+			Name:     "paragraphs",
+			HTML:     "<p>One <strong>bold</strong> and <em>emphasis </em>text.</p>\n<p>Two</p>",
+			Markdown: "One **bold** and *emphasis* text.\n\nTwo",
+		}, {
+			Name:     "headings and links",
+			HTML:     `<h2>A <a href="https://write.as">link</a></h2><p><img src="a.png" alt="A"></p>`,
+			Markdown: "## A [link](https://write.as)\n\n![A](a.png)",
+		}, {
+			Name:     "lists",
+			HTML:     "<ul><li>one</li><li>two<ol><li>nested</li></ol></li></ul>",
+			Markdown: "- one\n- two\n\n  1. nested",
+		}, {
+			Name:     "blockquote",
+			HTML:     "<blockquote><p>quoted</p><p>twice</p></blockquote>",
+			Markdown: "> quoted\n>\n> twice",
+		}, {
+			Name:     "code",
+			HTML:     "<p>Run <code>go test</code></p><pre><code class=\"language-go\">func main() {\n\tfmt.Println()\n}</code></pre>",
+			Markdown: "Run `go test`\n\n```go\nfunc main() {\n\tfmt.Println()\n}\n```",
+		}, {
+			Name:     "skipped elements",
+			HTML:     "<script>alert(1)</script><style>p{}</style><div>text<br>more</div><hr>",
+			Markdown: "text  \nmore\n\n---",
+		}, {
+			Name:     "escaped emphasis",
+			HTML:     "<p>*not emphasis* and snake_case_name</p>",
+			Markdown: "\\*not emphasis\\* and snake\\_case\\_name",
+		}, {
+			Name:     "escaped list",
+			HTML:     "<p>1. not a list</p><p>- nor this<br>+ or this</p>",
+			Markdown: "1\\. not a list\n\n\\- nor this  \n\\+ or this",
+		}, {
+			Name:     "escaped heading and blockquote",
+			HTML:     "<p># not heading</p><div>&gt; not quoted</div>",
+			Markdown: "\\# not heading\n\n\\> not quoted",
+		}, {
+			Name:     "escaped code and brackets",
+			HTML:     "<p>a `tick`, [brackets] and C:\\path</p>",
+			Markdown: "a \\`tick\\`, \\[brackets\\] and C:\\\\path",
+		}, {
+			Name:     "escaped links",
+			HTML:     `<p><a href="https://example.com/a_(b)">a [b] c</a> <img src="x y.png" alt="[x]"></p>`,
+			Markdown: "[a \\[b\\] c](https://example.com/a_\\(b\\)) ![\\[x\\]](x%20y.png)",
+		}, {
+			Name:     "escaped markup",
+			HTML:     "<p>&lt;script&gt;alert(1)&lt;/script&gt; &amp;amp; R&amp;D</p>",
+			Markdown: "&lt;script>alert(1)&lt;/script> &amp;amp; R&amp;D",
+		}, {
+			Name:     "unescaped code",
+			HTML:     "<p><code>*ptr</code></p><pre># comment\n1. step</pre>",
+			Markdown: "`*ptr`\n\n```\n# comment\n1. step\n```",
+		}, {
+			Name:     "checkboxes",
+			HTML:     `<ul><li><input type="checkbox" checked> done</li><li><input type="checkbox"> todo</li></ul>`,
+			Markdown: "- [x] done\n- [ ] todo",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tc.HTML))
+			if err != nil {
+				t.Fatalf("failed to parse html: %v", err)
+			}
+			md := htmlToMarkdown(doc, nil)
+			if md != tc.Markdown {
+				t.Fatalf("got markdown:\n%q\nexpected:\n%q", md, tc.Markdown)
+			}
+		})
+	}
+}
+
+func TestHTMLToMarkdownContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	md, err := htmlStringToMarkdown(ctx, "<p>one</p><p>two</p>")
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if md != "" {
+		t.Fatalf("got markdown %q but expected none", md)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// MediumKey is the key for published Medium posts in a ZipCollections
+	// map when the author could not be determined from the export.
+	MediumKey = "medium"
+
+	mediumDraftPrefix = "draft_"
+	mediumPostsDir    = "posts/"
+)
+
+// mediumIDRx matches the hex post ID Medium appends to slugs and filenames.
+var mediumIDRx = regexp.MustCompile(`-[0-9a-f]{8,12}$`)
+
+// FromMedium opens a Medium export archive and returns the posts it contains
+// and an error if any.
+//
+// Published posts are keyed by the author's Medium username and draft posts,
+// those files prefixed with draft_, are included under DraftsKey.
+func FromMedium(archive string, opts ...Option) (ZipCollections, error) {
+	return fromMedium(archive, newOptions(opts))
+}
+
+// FromMediumContext works as FromMedium, checking ctx between posts and while
+// converting them. Once ctx is done the posts read until then are returned
+// along with ctx.Err().
+func FromMediumContext(ctx context.Context, archive string, opts ...Option) (ZipCollections, error) {
+	return fromMedium(archive, contextOptions(ctx, opts))
+}
+
+func fromMedium(archive string, o *options) (ZipCollections, error) {
+	a, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	out := ZipCollections{DraftsKey: []*writeas.PostParams{}}
+	postErrors, err := walkZipFiles(archive, a.File, ZipContextFunc(MediumZipContextFunc).parser(), func(coll string, p *writeas.PostParams) error {
+		if p == nil {
+			return nil
+		}
+		key := DraftsKey
+		if p.Collection != "" {
+			p.Collection = o.collectionKey(p.Collection)
+			key = p.Collection
+		}
+		out[key] = append(out[key], p)
+		return nil
+	}, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return out, err
+}
+
+// MediumZipFunc parses the HTML post files in the posts directory of a Medium
+// export archive. The article body is converted to Markdown and the title,
+// publish date and slug are taken from the document, the slug being derived
+// from the canonical URL. Drafts are returned without a collection.
+func MediumZipFunc(f *zip.File) (*writeas.PostParams, error) {
+	return MediumZipContextFunc(context.Background(), f)
+}
+
+// MediumZipContextFunc works as MediumZipFunc, giving up on converting a post
+// once ctx is done.
+func MediumZipContextFunc(ctx context.Context, f *zip.File) (*writeas.PostParams, error) {
+	name := f.FileHeader.Name
+	if f.FileInfo().IsDir() || !strings.HasPrefix(name, mediumPostsDir) || path.Ext(name) != ".html" {
+		return nil, nil
+	}
+	b, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return parseMediumPost(ctx, b, path.Base(name))
+}
+
+func parseMediumPost(ctx context.Context, b []byte, filename string) (*writeas.PostParams, error) {
+	if len(b) == 0 {
+		return nil, ErrEmptyFile
+	}
+	doc, err := html.Parse(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &writeas.PostParams{}
+	if n := findNode(doc, classMatch("p-name")); n != nil {
+		p.Title = singleLine(textContent(n))
+	} else if n := findNode(doc, atomMatch(atom.Title)); n != nil {
+		p.Title = singleLine(textContent(n))
+	}
+
+	body := findNode(doc, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && attr(n, "data-field") == "body"
+	})
+	if body == nil {
+		body = findNode(doc, atomMatch(atom.Body))
+	}
+	if body != nil {
+		// the body repeats the title as its first heading
+		p.Content, err = htmlToMarkdownContext(ctx, body, classMatch("graf--title"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.Content == "" {
+		return nil, ErrEmptyFile
+	}
+
+	if n := findNode(doc, classMatch("dt-published")); n != nil {
+		if t, err := time.Parse(time.RFC3339, attr(n, "datetime")); err == nil {
+			p.Created = &t
+		}
+	}
+
+	if n := findNode(doc, classMatch("p-canonical")); n != nil {
+		p.Slug = mediumSlug(attr(n, "href"))
+	}
+	if p.Slug == "" {
+		p.Slug = mediumFilenameSlug(filename)
+	}
+
+	if !strings.HasPrefix(filename, mediumDraftPrefix) {
+		p.Collection = MediumKey
+		if n := findNode(doc, classMatch("p-author")); n != nil {
+			if user := mediumUsername(attr(n, "href")); user != "" {
+				p.Collection = user
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// mediumSlug returns the slug from a Medium post URL, such as
+// https://medium.com/@user/my-post-1a2b3c4d5e6f, without the trailing ID.
+func mediumSlug(postURL string) string {
+	u, err := url.Parse(postURL)
+	if err != nil {
+		return ""
+	}
+	seg := path.Base(strings.TrimSuffix(u.Path, "/"))
+	slug := mediumIDRx.ReplaceAllString(seg, "")
+	if slug == seg && !strings.Contains(seg, "-") {
+		// URLs such as /p/1a2b3c4d5e6f only hold the ID
+		return ""
+	}
+	return strings.ToLower(slug)
+}
+
+// mediumFilenameSlug derives a slug from an export filename such as
+// 2019-03-10_My-Post-1a2b3c4d5e6f.html or draft_My-Post-1a2b3c4d5e6f.html.
+func mediumFilenameSlug(filename string) string {
+	name := strings.TrimSuffix(filename, path.Ext(filename))
+	name = strings.TrimPrefix(name, mediumDraftPrefix)
+	if i := strings.IndexRune(name, '_'); i != -1 {
+		if _, err := time.Parse("2006-01-02", name[:i]); err == nil {
+			name = name[i+1:]
+		}
+	}
+	return strings.ToLower(mediumIDRx.ReplaceAllString(name, ""))
+}
+
+// mediumUsername returns the username from a profile URL such as
+// https://medium.com/@user.
+func mediumUsername(profileURL string) string {
+	u, err := url.Parse(profileURL)
+	if err != nil {
+		return ""
+	}
+	for _, seg := range strings.Split(u.Path, "/") {
+		if strings.HasPrefix(seg, "@") {
+			return strings.TrimPrefix(seg, "@")
+		}
+	}
+	return ""
+}
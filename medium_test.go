@@ -0,0 +1,63 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"testing"
+)
+
+const mediumPost = `<!DOCTYPE html><html><head><title>My First Post</title></head><body>
+<article class="h-entry">
+<header><h1 class="p-name">My First Post</h1></header>
+<section data-field="subtitle" class="p-summary">A subtitle</section>
+<section data-field="body" class="e-content">
+<section class="section section--body"><div class="section-content"><div class="section-inner">
+<h3 class="graf graf--h3 graf--leading graf--title">My First Post</h3>
+<p class="graf graf--p">Hello <strong class="markup--strong">Medium</strong>.</p>
+</div></div></section>
+</section>
+<footer><p>By <a href="https://medium.com/@writer" class="p-author h-card">Writer</a> on
+<a href="https://medium.com/p/1a2b3c4d5e6f"><time class="dt-published" datetime="2019-03-10T12:30:00.000Z">March 10, 2019</time></a>.</p>
+<p><a href="https://medium.com/@writer/my-first-post-1a2b3c4d5e6f" class="p-canonical">Canonical link</a></p></footer>
+</article></body></html>`
+
+var mediumFiles = fileList{
+	{"profile/profile.html", "<html><body>profile</body></html>"},
+	{"posts/2019-03-10_My-First-Post-1a2b3c4d5e6f.html", mediumPost},
+	{"posts/draft_Unfinished-9f8e7d6c5b4a.html", "<html><body><section data-field=\"body\"><p>Not done</p></section></body></html>"},
+}
+
+func TestFromMedium(t *testing.T) {
+	a := getTestZip(t, mediumFiles)
+	colls, err := FromMedium(a)
+	if err != nil {
+		t.Fatalf("failed to get posts from medium archive: %v", err)
+	}
+	if len(colls[DraftsKey]) != 1 {
+		t.Fatalf("draft count mismatch: got %d, expecting 1", len(colls[DraftsKey]))
+	}
+	if colls[DraftsKey][0].Slug != "unfinished" {
+		t.Fatalf("got draft slug %q but expected %q", colls[DraftsKey][0].Slug, "unfinished")
+	}
+	if len(colls["writer"]) != 1 {
+		t.Fatalf("post count mismatch: got %d, expecting 1", len(colls["writer"]))
+	}
+
+	p := colls["writer"][0]
+	if p.Title != "My First Post" {
+		t.Fatalf("got title %q but expected %q", p.Title, "My First Post")
+	}
+	if p.Slug != "my-first-post" {
+		t.Fatalf("got slug %q but expected %q", p.Slug, "my-first-post")
+	}
+	if p.Content != "Hello **Medium**." {
+		t.Fatalf("got content %q but expected %q", p.Content, "Hello **Medium**.")
+	}
+	if p.Created == nil || p.Created.Year() != 2019 {
+		t.Fatalf("got created %v but expected a 2019 date", p.Created)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Option configures how an importer reads its source.
+type Option func(*options)
+
+// options holds the settings shared by importers that accept Options.
+type options struct {
+	ctx     context.Context
+	pattern string
+	workers int
+	report  *Report
+	policy  ErrorPolicy
+
+	title       TitleStrategy
+	titleMaxLen int
+
+	reblogs    bool
+	collection string
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		ctx:         context.Background(),
+		workers:     1,
+		titleMaxLen: DefaultTitleMaxLength,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMatch limits an import to files whose name matches pattern. The pattern
+// should be a valid regex, for more details see
+// https://golang.org/s/re2syntax or run `go doc regexp/syntax`
+func WithMatch(pattern string) Option {
+	return func(o *options) {
+		o.pattern = pattern
+	}
+}
+
+// WithWorkers parses files across n goroutines, or runtime.GOMAXPROCS(0) of
+// them if n is less than 1. Posts are returned in the same order as they
+// would be when parsed one at a time.
+//
+// It is used by the directory, fs.FS, static site and zip imports, including
+// FromMedium, FromTumblr and FromSubstack. The tar imports and those reading
+// a single export file, such as FromGhost or FromWordPress, parse one post
+// at a time whatever n is.
+//
+// Any ZipFunc used alongside it must be safe to call concurrently, as must
+// the fs.FS passed to FromFS.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		if n < 1 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		o.workers = n
+	}
+}
+
+// WithContext stops an import once ctx is done, the import then returns the
+// posts read so far and ctx.Err(). The ...Context variants of the importers
+// are a shorthand for it.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// ErrorPolicy decides what an import does with a file that fails to be read
+// or parsed, or with a post of an export file that fails to be converted.
+// Empty and binary files are skipped by every import other than FromDirectory
+// whatever the policy, and only show up in a Report.
+type ErrorPolicy int
+
+const (
+	// SkipAndCollect leaves failing files out and returns their errors
+	// together as a *multierror.Error of FileErrors once the import is done.
+	// It is the default.
+	SkipAndCollect ErrorPolicy = iota
+	// FailFast stops the import at the first failing file and returns its
+	// error alone, without any posts.
+	FailFast
+	// SkipSilently leaves failing files out without returning their errors,
+	// use WithReport to find out about them.
+	SkipSilently
+)
+
+// WithErrorPolicy sets what an import does with files that fail to be read
+// or parsed, see ErrorPolicy.
+func WithErrorPolicy(p ErrorPolicy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// WithCollection keys all published posts of an export under collection,
+// rather than by the blog, author or notebook the export gives them. Drafts
+// stay under DraftsKey.
+//
+// It is used by the imports of exports from other platforms: FromBlogger,
+// FromDayOne, FromENEX, FromENEXNotebooks, FromGhost, FromMedium,
+// FromSubstack, FromTumblr, FromWordPress and FromWriteFreelyJSON. Imports
+// of files, directories and static sites key posts by where they are
+// found and do not use it.
+func WithCollection(collection string) Option {
+	return func(o *options) {
+		o.collection = collection
+	}
+}
+
+// collectionKey returns the collection given with WithCollection for a
+// published post that an export keys under key, or key if there is none.
+func (o *options) collectionKey(key string) string {
+	if o.collection == "" || key == DraftsKey {
+		return key
+	}
+	return o.collection
+}
+
+// fileFailed handles err from a single file according to the error policy.
+// It returns the error the import should stop with, collecting err into
+// postErrors instead if it should carry on. Context errors always stop it.
+func (o *options) fileFailed(postErrors *error, err error) error {
+	if err == nil || canceled(o.ctx, err) || o.policy == FailFast {
+		return err
+	}
+	if o.policy == SkipAndCollect {
+		*postErrors = multierror.Append(*postErrors, err)
+	}
+	return nil
+}
+
+// postFailed handles err from converting the post id of an export, read
+// from the file at path or from an io.Reader if path is empty, as fileFailed
+// does for a file.
+func (o *options) postFailed(postErrors *error, path, id string, err error) error {
+	if err == nil || canceled(o.ctx, err) {
+		return err
+	}
+	err = fmt.Errorf("post %s: %w", id, err)
+	if path != "" {
+		err = fileError(path, "", err)
+	}
+	return o.fileFailed(postErrors, err)
+}
+
+// contextOptions builds the options for a ...Context entry point, ctx taking
+// precedence over any WithContext among opts.
+func contextOptions(ctx context.Context, opts []Option) *options {
+	o := newOptions(opts)
+	o.ctx = ctx
+	return o
+}
+
+// canceled reports whether err is the error of ctx being done. Importers
+// return the posts read until then along with it.
+func canceled(ctx context.Context, err error) bool {
+	ctxErr := ctx.Err()
+	return ctxErr != nil && errors.Is(err, ctxErr)
+}
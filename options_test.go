@@ -0,0 +1,197 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/writeas/go-writeas/v2"
+)
+
+var errCorrupt = errors.New("corrupt entry")
+
+func TestErrorPolicy(t *testing.T) {
+	zipArchive := getTestZip(t, filesWDirs)
+	tarArchive := getTestTar(t, filesWDirs, ".tar", compressions[0].Writer)
+	defer os.Remove(tarArchive)
+	fsys := fstest.MapFS{}
+	for _, f := range filesWDirs {
+		fsys[f.Name] = &fstest.MapFile{Data: []byte(f.Contents)}
+	}
+
+	importers := []struct {
+		Name   string
+		Import func(opts ...Option) (ZipCollections, error)
+	}{
+		{"zip", func(opts ...Option) (ZipCollections, error) {
+			return FromZipDirsByFunc(zipArchive, func(f *zip.File) (*writeas.PostParams, error) {
+				if f.Name == "blog/post1.txt" {
+					return nil, errCorrupt
+				}
+				return TopLevelZipFunc(f)
+			}, opts...)
+		}},
+		{"tar", func(opts ...Option) (ZipCollections, error) {
+			return FromTarDirsByFunc(tarArchive, func(h *tar.Header, r io.Reader) (*writeas.PostParams, error) {
+				if tarEntryName(h) == "blog/post1.txt" {
+					return nil, errCorrupt
+				}
+				return TopLevelTarFunc(h, r)
+			}, opts...)
+		}},
+		{"fs", func(opts ...Option) (ZipCollections, error) {
+			return FromFS(failingFS{fsys, "blog/post1.txt"}, ".", opts...)
+		}},
+	}
+
+	for _, imp := range importers {
+		t.Run(imp.Name, func(t *testing.T) {
+			colls, err := imp.Import(WithErrorPolicy(FailFast))
+			if err == nil || colls != nil {
+				t.Fatalf("got %v, %v but expected the import to fail", colls, err)
+			}
+			var fileErr *FileError
+			if !errors.As(err, &fileErr) || fileErr.Path != "blog/post1.txt" {
+				t.Fatalf("got error %v but expected a FileError for blog/post1.txt", err)
+			}
+
+			colls, err = imp.Import()
+			merr, ok := err.(*multierror.Error)
+			if !ok || len(merr.Errors) != 1 {
+				t.Fatalf("got error %v but expected one collected error", err)
+			}
+			fileErr = nil
+			if !errors.As(err, &fileErr) || fileErr.Path != "blog/post1.txt" {
+				t.Fatalf("got error %v but expected to find a FileError for blog/post1.txt in it", err)
+			}
+			if !errors.Is(err, errCorrupt) {
+				t.Fatalf("got error %v but expected to find %v in it", err, errCorrupt)
+			}
+			if len(colls["blog"]) != 1 || len(colls["notes"]) != 1 {
+				t.Fatalf("got %d blog and %d notes posts but expected 1 of each", len(colls["blog"]), len(colls["notes"]))
+			}
+
+			colls, err = imp.Import(WithErrorPolicy(SkipSilently))
+			if err != nil {
+				t.Fatalf("got error %v but expected none", err)
+			}
+			if len(colls["blog"]) != 1 {
+				t.Fatalf("got %d blog posts but expected 1", len(colls["blog"]))
+			}
+		})
+	}
+}
+
+// failingFS fails to open the file named bad.
+type failingFS struct {
+	fsys fstest.MapFS
+	bad  string
+}
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	if name == f.bad {
+		return nil, errCorrupt
+	}
+	return f.fsys.Open(name)
+}
+
+func TestWithCollection(t *testing.T) {
+	ghost, err := ioutil.TempFile("", "ghost-*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(ghost.Name())
+	if _, err := ghost.WriteString(ghostExportJSON); err != nil {
+		t.Fatalf("failed to write ghost export: %v", err)
+	}
+	ghost.Close()
+
+	tt := []struct {
+		Name string
+		// Files are written to the archive passed to Import, if any
+		Files  fileList
+		Import func(archive string, opts ...Option) (ZipCollections, error)
+	}{
+		{"Blogger", nil, func(_ string, opts ...Option) (ZipCollections, error) {
+			return FromBlogger(strings.NewReader(bloggerFeed), opts...)
+		}},
+		{"DayOne", fileList{{"Journal.json", dayOneJSON}}, FromDayOne},
+		{"ENEX", nil, func(_ string, opts ...Option) (ZipCollections, error) {
+			return FromENEX(strings.NewReader(enex), opts...)
+		}},
+		{"Ghost", nil, func(_ string, opts ...Option) (ZipCollections, error) {
+			return FromGhost(ghost.Name(), opts...)
+		}},
+		{"Medium", mediumFiles, FromMedium},
+		{"Substack", fileList{
+			{"posts.csv", substackCSV},
+			{"posts/1001.first-issue.html", `<p>Hello.</p>`},
+			{"posts/1002.next-time.html", `<p>Coming soon.</p>`},
+		}, FromSubstack},
+		{"Tumblr", fileList{{"posts.xml", tumblrXML}}, FromTumblr},
+		{"WordPress", nil, func(_ string, opts ...Option) (ZipCollections, error) {
+			colls, _, err := fromWXR(strings.NewReader(wxr), "", []string{"post"}, newOptions(opts))
+			return colls, err
+		}},
+		{"WriteFreelyJSON", nil, func(_ string, opts ...Option) (ZipCollections, error) {
+			return FromWriteFreelyJSON(strings.NewReader(wfExportJSON), opts...)
+		}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var archive string
+			if tc.Files != nil {
+				archive = getTestZip(t, tc.Files)
+				defer os.Remove(archive)
+			}
+			colls, err := tc.Import(archive)
+			if err != nil {
+				t.Fatalf("failed to import: %v", err)
+			}
+			published := 0
+			for key, posts := range colls {
+				if key != DraftsKey {
+					published += len(posts)
+				}
+			}
+			if published == 0 {
+				t.Fatalf("got no published posts to move")
+			}
+
+			moved, err := tc.Import(archive, WithCollection("site"))
+			if err != nil {
+				t.Fatalf("failed to import with a collection: %v", err)
+			}
+			if len(moved["site"]) != published {
+				t.Fatalf("got %d posts in the collection but expected %d", len(moved["site"]), published)
+			}
+			for _, p := range moved["site"] {
+				if p.Collection != "site" {
+					t.Fatalf("got post in collection %q but expected %q", p.Collection, "site")
+				}
+			}
+			if len(moved[DraftsKey]) != len(colls[DraftsKey]) {
+				t.Fatalf("got %d drafts but expected %d", len(moved[DraftsKey]), len(colls[DraftsKey]))
+			}
+			for key, posts := range moved {
+				if key != "site" && key != DraftsKey && len(posts) > 0 {
+					t.Fatalf("got %d posts under %q but expected them in the collection", len(posts), key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+// Parser converts the contents b of the file name into a post. It should
+// return ErrInvalidContentType for content it cannot make sense of, and
+// ErrEmptyFile for content that holds no post.
+type Parser func(name string, b []byte) (*writeas.PostParams, error)
+
+// fileParser is the internal form of Parser, also returning the front matter
+// and encoding of the file for the package's own parsers.
+type fileParser func(name string, b []byte) (*writeas.PostParams, *frontMatter, string, error)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[string]fileParser{}
+)
+
+func init() {
+	for _, key := range []string{
+		".txt", ".text", "text/plain", "text/*",
+		".md", ".markdown", ".mdown", ".mkd", ".mkdn", "text/markdown",
+	} {
+		registerParser(key, parseText)
+	}
+}
+
+// RegisterParser makes p the parser for files with the extension or MIME
+// type key, such as ".docx" or "application/rtf", replacing any parser
+// registered for it before. A MIME type key may also end in /* to match the
+// whole type, as the built-in "text/*" does.
+//
+// Files are matched on their extension first and, without a parser for it,
+// on the MIME type detected from their content by http.DetectContentType.
+// Files no parser is registered for are skipped with ErrInvalidContentType.
+//
+// Plain text and Markdown parsers are registered for .txt, .md and the
+// other common extensions of both, and for all text/* files. An HTML parser,
+// converting the document to Markdown, is registered for .html, .htm and
+// text/html.
+func RegisterParser(key string, p Parser) {
+	registerParser(key, func(name string, b []byte) (*writeas.PostParams, *frontMatter, string, error) {
+		post, err := p(name, b)
+		return post, nil, "", err
+	})
+}
+
+// UnregisterParser removes the parser registered for key, as passed to
+// RegisterParser, so that files matching it are handled as if it had never
+// been registered. Built-in parsers may be removed too.
+func UnregisterParser(key string) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	delete(parsers, strings.ToLower(key))
+}
+
+func registerParser(key string, p fileParser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[strings.ToLower(key)] = p
+}
+
+// lookupParser returns the parser for the file name with the contents b, or
+// nil if there is none.
+func lookupParser(name string, b []byte) fileParser {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	if ext := strings.ToLower(path.Ext(name)); ext != "" {
+		if p, ok := parsers[ext]; ok {
+			return p
+		}
+	}
+	mediaType := sniffMediaType(b)
+	if p, ok := parsers[mediaType]; ok {
+		return p
+	}
+	if i := strings.IndexByte(mediaType, '/'); i != -1 {
+		return parsers[mediaType[:i]+"/*"]
+	}
+	return nil
+}
+
+// sniffMediaType returns the MIME type of b, without parameters. UTF-16
+// text is recognized as text/plain even though it looks binary.
+func sniffMediaType(b []byte) string {
+	if enc := detectEncoding(b); enc == EncodingUTF16LE || enc == EncodingUTF16BE {
+		return "text/plain"
+	}
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(b))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// parseFile parses the contents b of the file name into a post with the
+// parser registered for it, see RegisterParser.
+func parseFile(name string, b []byte) (*writeas.PostParams, *frontMatter, string, error) {
+	if len(b) == 0 {
+		return nil, nil, "", ErrEmptyFile
+	}
+	p := lookupParser(name, b)
+	if p == nil {
+		return nil, nil, "", ErrInvalidContentType
+	}
+	post, fm, enc, err := p(name, b)
+	if err == nil && post == nil {
+		err = ErrEmptyFile
+	}
+	return post, fm, enc, err
+}
+
+// parseText is the built-in parser for plain text and Markdown files. Plain
+// text is read as Markdown, a leading heading becoming the title.
+func parseText(name string, b []byte) (*writeas.PostParams, *frontMatter, string, error) {
+	return parseBytes(b)
+}
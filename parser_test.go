@@ -0,0 +1,199 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+func TestParseFile(t *testing.T) {
+	tt := []struct {
+		Name    string
+		File    string
+		Bytes   []byte
+		Content string
+		Error   error
+	}{
+		{
+			Name:    "text",
+			File:    "post.txt",
+			Bytes:   []byte("Some text."),
+			Content: "Some text.",
+		}, {
+			Name:    "markdown by extension",
+			File:    "post.MD",
+			Bytes:   []byte("# Title\n\nBody."),
+			Content: "Body.",
+		}, {
+			Name:  "binary markdown",
+			File:  "post.md",
+			Bytes: []byte("\x00\x01\x02 binary"),
+			Error: ErrInvalidContentType,
+		}, {
+			Name:    "markdown with a control byte",
+			File:    "post.md",
+			Bytes:   []byte("Pasted from Word\x0b with a tab."),
+			Content: "Pasted from Word\x0b with a tab.",
+		}, {
+			Name:  "text with a control byte without extension",
+			File:  "README",
+			Bytes: []byte("Pasted from Word\x0b with a tab."),
+			Error: ErrInvalidContentType,
+		}, {
+			Name:    "text without extension",
+			File:    "README",
+			Bytes:   []byte("Some text."),
+			Content: "Some text.",
+		}, {
+			Name:    "utf-16 without extension",
+			File:    "notes",
+			Bytes:   utf16Bytes("Some plain text", binary.LittleEndian, false),
+			Content: "Some plain text",
+		}, {
+			Name:  "image",
+			File:  "image.png",
+			Bytes: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"),
+			Error: ErrInvalidContentType,
+		}, {
+			Name:  "empty",
+			File:  "post.md",
+			Bytes: []byte{},
+			Error: ErrEmptyFile,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, _, _, err := parseFile(tc.File, tc.Bytes)
+			if !errors.Is(err, tc.Error) {
+				t.Fatalf("got error %v but expected %v", err, tc.Error)
+			}
+			if err != nil {
+				return
+			}
+			if p.Content != tc.Content {
+				t.Fatalf("got content %q but expected %q", p.Content, tc.Content)
+			}
+		})
+	}
+}
+
+func TestRegisterParser(t *testing.T) {
+	RegisterParser(".test-upper", func(name string, b []byte) (*writeas.PostParams, error) {
+		return &writeas.PostParams{Title: name, Content: strings.ToUpper(string(b))}, nil
+	})
+	RegisterParser(".test-nothing", func(name string, b []byte) (*writeas.PostParams, error) {
+		return nil, nil
+	})
+	t.Cleanup(func() {
+		UnregisterParser(".test-upper")
+		UnregisterParser(".test-nothing")
+	})
+
+	tt := []struct {
+		Name    string
+		File    string
+		Bytes   []byte
+		Title   string
+		Content string
+		Error   error
+	}{
+		{
+			Name:    "by extension",
+			File:    "post.TEST-UPPER",
+			Bytes:   []byte("shout"),
+			Title:   "post.TEST-UPPER",
+			Content: "SHOUT",
+		}, {
+			Name:  "no post",
+			File:  "post.test-nothing",
+			Bytes: []byte("text"),
+			Error: ErrEmptyFile,
+		}, {
+			Name:  "unregistered type",
+			File:  "doc.pdf",
+			Bytes: []byte("%PDF-1.4\n"),
+			Error: ErrInvalidContentType,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, _, _, err := parseFile(tc.File, tc.Bytes)
+			if !errors.Is(err, tc.Error) {
+				t.Fatalf("got error %v but expected %v", err, tc.Error)
+			}
+			if err != nil {
+				return
+			}
+			if p.Title != tc.Title {
+				t.Fatalf("got title %q but expected %q", p.Title, tc.Title)
+			}
+			if p.Content != tc.Content {
+				t.Fatalf("got content %q but expected %q", p.Content, tc.Content)
+			}
+		})
+	}
+}
+
+func TestRegisterParserImport(t *testing.T) {
+	RegisterParser(".test-rev", func(name string, b []byte) (*writeas.PostParams, error) {
+		r := []rune(string(b))
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return &writeas.PostParams{Content: string(r)}, nil
+	})
+	t.Cleanup(func() { UnregisterParser(".test-rev") })
+
+	fsys := fstest.MapFS{
+		"blog/post.test-rev": {Data: []byte("desrever")},
+		"blog/post.txt":      {Data: []byte("plain")},
+	}
+	colls, err := FromFS(fsys, ".")
+	if err != nil {
+		t.Fatalf("failed to get posts from fs: %v", err)
+	}
+	posts := colls["blog"]
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts but expected 2", len(posts))
+	}
+	if posts[0].Content != "reversed" {
+		t.Fatalf("got content %q but expected %q", posts[0].Content, "reversed")
+	}
+	if posts[0].Created == nil {
+		t.Fatalf("got no creation date but expected the modification time")
+	}
+}
+
+func TestUnregisterParser(t *testing.T) {
+	RegisterParser(".test-gone", func(name string, b []byte) (*writeas.PostParams, error) {
+		return &writeas.PostParams{Content: string(b)}, nil
+	})
+	if _, _, _, err := parseFile("post.test-gone", []byte("text")); err != nil {
+		t.Fatalf("failed to parse with the registered parser: %v", err)
+	}
+
+	UnregisterParser(".TEST-GONE")
+	// the extension no longer matches, the content is still sniffed as text
+	p, _, _, err := parseFile("post.test-gone", []byte("# Title\n\nBody."))
+	if err != nil {
+		t.Fatalf("failed to parse as text: %v", err)
+	}
+	if p.Title != "Title" {
+		t.Fatalf("got title %q but expected %q", p.Title, "Title")
+	}
+	if _, _, _, err := parseFile("post.test-gone", []byte("\x00\x01\x02 binary")); !errors.Is(err, ErrInvalidContentType) {
+		t.Fatalf("got error %v but expected %v", err, ErrInvalidContentType)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"sync"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+type parseResult struct {
+	post *writeas.PostParams
+	err  error
+}
+
+// parseOrdered calls parse for every index from 0 to n-1 using up to workers
+// goroutines and passes each result to emit in index order, so the output
+// does not depend on which parse finishes first. Only a window of results
+// proportional to workers is held at once.
+//
+// It stops when ctx is done, returning ctx.Err(), or when emit returns an
+// error, returning that error. All calls to parse have returned by the time
+// parseOrdered does.
+func parseOrdered(ctx context.Context, workers, n int, parse func(i int) (*writeas.PostParams, error), emit func(i int, p *writeas.PostParams, err error) error) error {
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			p, err := parse(i)
+			if err := emit(i, p, err); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	type job struct {
+		i   int
+		out chan<- parseResult
+	}
+	jobs := make(chan job)
+	pending := make(chan chan parseResult, workers)
+
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		// drain so the producer is not left blocked on a full window
+		for range pending {
+		}
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		defer close(pending)
+		for i := 0; i < n; i++ {
+			out := make(chan parseResult, 1)
+			select {
+			case pending <- out:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{i, out}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				p, err := parse(j.i)
+				j.out <- parseResult{p, err}
+			}
+		}()
+	}
+
+	i := 0
+	for out := range pending {
+		var r parseResult
+		select {
+		case r = <-out:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := emit(i, r.post, r.err); err != nil {
+			return err
+		}
+		i++
+	}
+	if i < n {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+func TestParseOrdered(t *testing.T) {
+	n := 50
+	parse := func(i int) (*writeas.PostParams, error) {
+		// later indexes finish first
+		time.Sleep(time.Duration(n-i) * 100 * time.Microsecond)
+		return &writeas.PostParams{Title: strconv.Itoa(i)}, nil
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		t.Run(strconv.Itoa(workers), func(t *testing.T) {
+			next := 0
+			err := parseOrdered(context.Background(), workers, n, parse, func(i int, p *writeas.PostParams, err error) error {
+				if i != next || p.Title != strconv.Itoa(i) {
+					t.Fatalf("got result %d (%s) but expected %d", i, p.Title, next)
+				}
+				next++
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+			if next != n {
+				t.Fatalf("got %d results, expecting %d", next, n)
+			}
+		})
+	}
+}
+
+func TestParseOrderedStop(t *testing.T) {
+	parse := func(i int) (*writeas.PostParams, error) {
+		return &writeas.PostParams{}, nil
+	}
+
+	stopErr := errors.New("stop")
+	calls := 0
+	err := parseOrdered(context.Background(), 4, 100, parse, func(i int, p *writeas.PostParams, err error) error {
+		calls++
+		if i == 10 {
+			return stopErr
+		}
+		return nil
+	})
+	if err != stopErr || calls != 11 {
+		t.Fatalf("got error %v after %d results, expecting %v after 11", err, calls, stopErr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = parseOrdered(ctx, 4, 100, parse, func(i int, p *writeas.PostParams, err error) error {
+		calls++
+		if i == 10 {
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("got error %v, expecting %v", err, context.Canceled)
+	}
+	if calls != 11 {
+		t.Fatalf("parsing was not stopped by the context, got %d results", calls)
+	}
+}
+
+func TestFromZipWorkers(t *testing.T) {
+	a := getTestZip(t, filesWDirs)
+	expected, err := FromZip(a)
+	if err != nil {
+		t.Fatalf("failed to get posts from archive: %v", err)
+	}
+	posts, err := FromZip(a, WithWorkers(0))
+	if err != nil {
+		t.Fatalf("failed to get posts from archive: %v", err)
+	}
+	if len(posts) != len(expected) {
+		t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), len(expected))
+	}
+	for i := range posts {
+		if posts[i].Content != expected[i].Content {
+			t.Fatalf("post %d out of order: got %q but expected %q", i, posts[i].Content, expected[i].Content)
+		}
+	}
+}
+
+func TestFromFSWorkers(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < 30; i++ {
+		fsys["blog/post-"+strconv.Itoa(i)+".txt"] = &fstest.MapFile{Data: []byte("Post " + strconv.Itoa(i))}
+	}
+	expected, err := FromFS(fsys, ".")
+	if err != nil {
+		t.Fatalf("failed to get posts from fs: %v", err)
+	}
+	colls, err := FromFS(fsys, ".", WithWorkers(4))
+	if err != nil {
+		t.Fatalf("failed to get posts from fs: %v", err)
+	}
+	posts := colls["blog"]
+	if len(posts) != len(expected["blog"]) {
+		t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), len(expected["blog"]))
+	}
+	for i := range posts {
+		if posts[i].Content != expected["blog"][i].Content {
+			t.Fatalf("post %d out of order: got %q but expected %q", i, posts[i].Content, expected["blog"][i].Content)
+		}
+	}
+}
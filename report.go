@@ -0,0 +1,110 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"errors"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+// Outcome is what became of a single source file during an import.
+type Outcome int
+
+const (
+	// Imported files were parsed into a post.
+	Imported Outcome = iota
+	// SkippedEmpty files had no content.
+	SkippedEmpty
+	// SkippedBinary files were not text.
+	SkippedBinary
+	// Failed files could not be read or parsed.
+	Failed
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Imported:
+		return "imported"
+	case SkippedEmpty:
+		return "skipped-empty"
+	case SkippedBinary:
+		return "skipped-binary"
+	case Failed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// FileReport is the outcome of importing a single source file.
+type FileReport struct {
+	// Path is the name of the file relative to the directory, file system or
+	// archive it was imported from. For the posts of an export file, such as
+	// the notes of an ENEX export, it is the path of the export followed by
+	// # and the post, as in Travel.enex#Packing List, or the post alone for
+	// an export read from an io.Reader.
+	Path    string
+	Outcome Outcome
+	// Err is the reason the file was skipped or failed, nil if imported.
+	Err error
+	// Slug is the slug of the resulting post, if it had one.
+	Slug string
+	// Encoding is the character encoding the file was detected to be in
+	// and transcoded to UTF-8 from, such as EncodingWindows1252. It is empty
+	// if the file was not decoded by this package, as with all those parsed
+	// by a ZipFunc, ZipContextFunc or TarFunc given to an import.
+	Encoding string
+}
+
+// Report lists the outcome of every source file an import came across, in
+// the order they were read. Files left out by a pattern or a ZipFunc
+// returning no post are not included.
+type Report struct {
+	Files []FileReport
+}
+
+// WithReport records the outcome of each file read by an import in r. The
+// same Report should not be used by imports running concurrently.
+func WithReport(r *Report) Option {
+	return func(o *options) {
+		o.report = r
+	}
+}
+
+// record adds the result of parsing the file at path, detected to be in the
+// encoding enc, to the report if the import has one. Files interrupted by the
+// context being done are left out.
+func (o *options) record(path, enc string, p *writeas.PostParams, err error) {
+	if o.report == nil || canceled(o.ctx, err) {
+		return
+	}
+	fr := FileReport{Path: path, Err: err, Encoding: enc}
+	switch {
+	case err == nil:
+		fr.Outcome = Imported
+		if p != nil {
+			fr.Slug = p.Slug
+		}
+	case errors.Is(err, ErrEmptyFile):
+		fr.Outcome = SkippedEmpty
+	case errors.Is(err, ErrInvalidContentType):
+		fr.Outcome = SkippedBinary
+	default:
+		fr.Outcome = Failed
+	}
+	o.report.Files = append(o.report.Files, fr)
+}
+
+// recordPost adds the result of converting the post id of an export, read
+// from the file at path or from an io.Reader if path is empty, to the report
+// as record does for a file.
+func (o *options) recordPost(path, id string, p *writeas.PostParams, err error) {
+	if path != "" {
+		id = path + "#" + id
+	}
+	o.record(id, "", p, err)
+}
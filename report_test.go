@@ -0,0 +1,93 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/zip"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+func TestReport(t *testing.T) {
+	a := getTestZip(t, fileList{
+		{"post.txt", "This is a post from somewhere."},
+		{"empty.txt", ""},
+		{"image.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"},
+		{"blog/my-post_839ruu389ru9.md", "# Title\n\nBody"},
+	})
+
+	r := &Report{}
+	_, err := FromZipDirs(a, WithReport(r))
+	if err != nil {
+		t.Fatalf("getting posts from zip: %v", err)
+	}
+	tt := []FileReport{
+		{Path: "post.txt", Outcome: Imported, Encoding: EncodingUTF8},
+		{Path: "empty.txt", Outcome: SkippedEmpty, Err: ErrEmptyFile},
+		{Path: "image.png", Outcome: SkippedBinary, Err: ErrInvalidContentType},
+		{Path: "blog/my-post_839ruu389ru9.md", Outcome: Imported, Slug: "my-post", Encoding: EncodingUTF8},
+	}
+	if len(r.Files) != len(tt) {
+		t.Fatalf("got %d reported files but expected %d", len(r.Files), len(tt))
+	}
+	for i, tc := range tt {
+		if r.Files[i] != tc {
+			t.Fatalf("got report %+v but expected %+v", r.Files[i], tc)
+		}
+	}
+}
+
+func TestReportFailed(t *testing.T) {
+	a := getTestZip(t, files)
+	parseErr := errors.New("corrupt entry")
+
+	r := &Report{}
+	_, err := FromZipByFunc(a, func(f *zip.File) (*writeas.PostParams, error) {
+		return nil, parseErr
+	}, WithReport(r), WithErrorPolicy(FailFast))
+	if !errors.Is(err, parseErr) {
+		t.Fatalf("got error %v but expected %v", err, parseErr)
+	}
+	if len(r.Files) != 1 {
+		t.Fatalf("got %d reported files but expected 1", len(r.Files))
+	}
+	if r.Files[0].Outcome != Failed || r.Files[0].Err != parseErr {
+		t.Fatalf("got report %+v but expected a failure", r.Files[0])
+	}
+}
+
+func TestReportFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"post.md":        {Data: []byte("---\nslug: hello\n---\nHello")},
+		"blog/empty.txt": {Data: []byte{}},
+		".hidden.txt":    {Data: []byte("hidden")},
+	}
+
+	r := &Report{}
+	_, err := FromFS(fsys, ".", WithReport(r))
+	if err != nil {
+		t.Fatalf("getting posts from fs: %v", err)
+	}
+	if len(r.Files) != 2 {
+		t.Fatalf("got %d reported files but expected 2", len(r.Files))
+	}
+	if f := r.Files[0]; f.Path != "blog/empty.txt" || f.Outcome != SkippedEmpty {
+		t.Fatalf("got report %+v for the empty file", f)
+	}
+	if f := r.Files[1]; f.Path != "post.md" || f.Outcome != Imported || f.Slug != "hello" {
+		t.Fatalf("got report %+v for the post", f)
+	}
+}
+
+func TestOutcomeString(t *testing.T) {
+	if s := SkippedBinary.String(); s != "skipped-binary" {
+		t.Fatalf("got %q but expected %q", s, "skipped-binary")
+	}
+}
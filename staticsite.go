@@ -0,0 +1,602 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/writeas/go-writeas/v2"
+)
+
+// SiteKind is the static site generator a site directory is laid out for.
+type SiteKind int
+
+const (
+	// HugoSite reads the content directory of a Hugo site, each section
+	// becoming a collection.
+	HugoSite SiteKind = iota
+	// JekyllSite reads the _posts and _drafts directories of a Jekyll site.
+	JekyllSite
+)
+
+// JekyllKey is the key for published Jekyll posts in a ZipCollections map.
+const JekyllKey = "jekyll"
+
+const (
+	hugoContentDir = "content"
+	jekyllPostsDir = "_posts"
+	jekyllDraftDir = "_drafts"
+)
+
+// siteExts are the extensions of the content files of a static site, other
+// files such as the images of a page bundle are left out.
+var siteExts = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".mdown":    true,
+	".mkd":      true,
+	".html":     true,
+	".htm":      true,
+}
+
+// jekyllNameRx matches the date and slug in the name of a Jekyll post, such
+// as 2019-05-04-my-post.
+var jekyllNameRx = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+
+// sitePost is where a content file of a static site belongs.
+type sitePost struct {
+	coll string
+	slug string
+	// date is the date given by the file name, if any
+	date *time.Time
+}
+
+// FromStaticSite reads the posts of the Hugo or Jekyll site in the directory
+// root and returns them and an error if any.
+//
+// For Hugo sites every file in the content directory is read, except for the
+// _index files of sections. Posts are keyed by the section they are in and
+// slugged after their file name or, for page bundles, their directory. Pages
+// directly in the content directory are included under DraftsKey.
+//
+// For Jekyll sites the posts in _posts are keyed by JekyllKey, taking their
+// creation date and slug from names such as 2019-05-04-my-post.md, and those
+// in _drafts are included under DraftsKey.
+//
+// Posts with draft: true in their front matter are included under DraftsKey
+// for both. Shortcodes and Liquid tags are rendered where there is a
+// Markdown equivalent, such as for highlighted code, figures and embedded
+// videos, and are otherwise replaced by a placeholder naming them.
+func FromStaticSite(root string, kind SiteKind, opts ...Option) (ZipCollections, error) {
+	return fromStaticSite(root, kind, newOptions(opts))
+}
+
+// FromStaticSiteContext works as FromStaticSite, checking ctx between files.
+// Once ctx is done the collections read until then are returned along with
+// ctx.Err().
+func FromStaticSiteContext(ctx context.Context, root string, kind SiteKind, opts ...Option) (ZipCollections, error) {
+	return fromStaticSite(root, kind, contextOptions(ctx, opts))
+}
+
+func fromStaticSite(root string, kind SiteKind, o *options) (ZipCollections, error) {
+	var (
+		dirs   []string
+		locate func(name string) (sitePost, bool)
+		render func(content string) string
+	)
+	switch kind {
+	case HugoSite:
+		dirs, locate, render = []string{hugoContentDir}, hugoPost, renderShortcodes
+	case JekyllSite:
+		dirs, locate, render = []string{jekyllPostsDir, jekyllDraftDir}, jekyllPost, renderLiquid
+	default:
+		return nil, fmt.Errorf("unknown site kind %d", kind)
+	}
+
+	fsys, err := dirFS(root)
+	if err != nil {
+		return nil, err
+	}
+	out := make(ZipCollections)
+	postErrors, err := walkSite(fsys, dirs, siteLocator(locate, render), o, out.add)
+	if canceled(o.ctx, err) {
+		return out, err
+	} else if err != nil {
+		return nil, err
+	}
+	return out, postErrors
+}
+
+// walkSite visits the posts under dirs in fsys, as walkFS does for each of
+// them. Directories that do not exist are left out, unless none of them do.
+func walkSite(fsys fs.FS, dirs []string, locate fsLocateFunc, o *options, visit visitFunc) (postErrors, err error) {
+	var found []string
+	for _, dir := range dirs {
+		if _, statErr := fs.Stat(fsys, dir); statErr == nil {
+			found = append(found, dir)
+		} else if err == nil {
+			err = statErr
+		}
+	}
+	if len(found) == 0 {
+		return nil, err
+	}
+
+	for _, dir := range found {
+		dirErrors, err := walkFS(fsys, dir, locate, o, visit)
+		if dirErrors != nil {
+			postErrors = multierror.Append(postErrors, dirErrors)
+		}
+		if err != nil {
+			return postErrors, err
+		}
+	}
+	return postErrors, nil
+}
+
+// siteLocator returns the fsLocateFunc placing the content files of a static
+// site where locate says, their content being passed through render.
+func siteLocator(locate func(name string) (sitePost, bool), render func(content string) string) fsLocateFunc {
+	return func(name string) (string, fsResolveFunc) {
+		if !siteExts[strings.ToLower(path.Ext(name))] {
+			return "", nil
+		}
+		sp, ok := locate(name)
+		if !ok {
+			return "", nil
+		}
+		return sp.coll, func(post *writeas.PostParams, fm *frontMatter) string {
+			post.Content = render(post.Content)
+			if post.Slug == "" {
+				post.Slug = sp.slug
+			}
+			if sp.date != nil && (fm == nil || fm.Date == nil) {
+				post.Created = sp.date
+			}
+
+			coll := sp.coll
+			if fm != nil && fm.Draft {
+				coll = DraftsKey
+			}
+			if coll != DraftsKey {
+				post.Collection = coll
+			}
+			return coll
+		}
+	}
+}
+
+// hugoPost places the file name in the content directory of a Hugo site.
+func hugoPost(name string) (sitePost, bool) {
+	rel := relPath(hugoContentDir, name)
+	base := path.Base(rel)
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	if stem == "_index" {
+		return sitePost{}, false
+	}
+
+	sp := sitePost{coll: DraftsKey, slug: stem}
+	if seg := strings.Split(rel, "/"); len(seg) > 1 {
+		sp.coll = seg[0]
+	}
+	if stem == "index" {
+		// the content of a leaf bundle is named after its directory, while
+		// the home page of the site is left without a slug
+		sp.slug = bundleSlug(rel)
+	}
+	return sp, true
+}
+
+// jekyllPost places the file name in a Jekyll site. Only posts in _posts
+// named after their date and drafts in _drafts are included, as Jekyll
+// does.
+func jekyllPost(name string) (sitePost, bool) {
+	seg := strings.Split(name, "/")
+	base := path.Base(name)
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	switch seg[0] {
+	case jekyllPostsDir:
+		date, slug := jekyllName(stem)
+		if date == nil {
+			return sitePost{}, false
+		}
+		return sitePost{coll: JekyllKey, slug: slug, date: date}, true
+	case jekyllDraftDir:
+		return sitePost{coll: DraftsKey, slug: stem}, true
+	}
+	return sitePost{}, false
+}
+
+// jekyllName returns the date and slug from the name of a Jekyll post
+// without its extension, or a nil date if it is not named after one.
+func jekyllName(stem string) (*time.Time, string) {
+	m := jekyllNameRx.FindStringSubmatch(stem)
+	if m == nil {
+		return nil, stem
+	}
+	t, err := time.Parse("2006-01-02", m[1])
+	if err != nil {
+		return nil, stem
+	}
+	return &t, m[2]
+}
+
+var (
+	// shortcodeRx matches a Hugo shortcode tag, as in {{< name args >}} or
+	// {{% /name %}}.
+	shortcodeRx = regexp.MustCompile(`\{\{[<%]\s*(/?)\s*([\w.-]+)(.*?)\s*[>%]\}\}`)
+	// shortcodeHighlightRx matches a Hugo highlight shortcode and its code.
+	shortcodeHighlightRx = regexp.MustCompile(`(?s)\{\{[<%]\s*highlight\s+([\w+#-]*)[^}]*?[>%]\}\}\n?(.*?)\n?\{\{[<%]\s*/highlight\s*[>%]\}\}`)
+	// templateArgRx matches a named or positional shortcode argument.
+	templateArgRx = regexp.MustCompile("(?:([\\w-]+)=)?(\"[^\"]*\"|`[^`]*`|'[^']*'|\\S+)")
+
+	liquidCommentRx   = regexp.MustCompile(`(?s)\{%-?\s*comment\s*-?%\}.*?\{%-?\s*endcomment\s*-?%\}`)
+	liquidRawRx       = regexp.MustCompile(`(?s)\{%-?\s*raw\s*-?%\}(.*?)\{%-?\s*endraw\s*-?%\}`)
+	liquidHighlightRx = regexp.MustCompile(`(?s)\{%-?\s*highlight\s+([\w+#-]+)[^%]*?-?%\}\n?(.*?)\n?\{%-?\s*endhighlight\s*-?%\}`)
+	liquidTagRx       = regexp.MustCompile(`\{%-?\s*(\w+)(.*?)\s*-?%\}`)
+	liquidOutputRx    = regexp.MustCompile(`\{\{-?\s*(.*?)\s*-?\}\}`)
+	liquidURLFilterRx = regexp.MustCompile(`^(["'])(.*)(["'])\s*\|\s*(?:relative_url|absolute_url)$`)
+
+	// codeFenceRx matches the opening line of a fenced Markdown code block.
+	codeFenceRx = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	// listItemRx matches the first line of a Markdown list item.
+	listItemRx = regexp.MustCompile(`^ {0,3}(?:[-*+]|\d{1,9}[.)])(?:[ \t]|\r?\n|$)`)
+	// templateTagRx matches a shortcode or Liquid tag on a single line.
+	templateTagRx = regexp.MustCompile(`\{[{%].*?[}%]\}`)
+	// heldCodeRx matches the placeholders outsideCode swaps code for.
+	heldCodeRx = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// liquidControlTags are the Liquid tags that only control how the template
+// is rendered, they are dropped while the text between them is kept.
+var liquidControlTags = map[string]bool{
+	"if": true, "elsif": true, "else": true, "endif": true,
+	"unless": true, "endunless": true,
+	"case": true, "when": true, "endcase": true,
+	"for": true, "endfor": true, "break": true, "continue": true,
+	"capture": true, "endcapture": true, "assign": true,
+	"increment": true, "decrement": true,
+}
+
+// renderShortcodes renders the Hugo shortcodes in content that have a
+// Markdown equivalent and replaces the others with a placeholder. The tags of
+// shortcodes wrapping text are removed, keeping the text. Shortcodes in code
+// blocks and inline code are left as they are.
+func renderShortcodes(content string) string {
+	return outsideCode(content, renderShortcodeTags)
+}
+
+func renderShortcodeTags(content string) string {
+	content = shortcodeHighlightRx.ReplaceAllStringFunc(content, func(s string) string {
+		m := shortcodeHighlightRx.FindStringSubmatch(s)
+		return fenceCode(m[1], m[2])
+	})
+
+	closed := map[string]bool{}
+	for _, m := range shortcodeRx.FindAllStringSubmatch(content, -1) {
+		if m[1] == "/" {
+			closed[m[2]] = true
+		}
+	}
+	return shortcodeRx.ReplaceAllStringFunc(content, func(s string) string {
+		m := shortcodeRx.FindStringSubmatch(s)
+		name, rawArgs := m[2], strings.TrimSpace(m[3])
+		if m[1] == "/" || closed[name] {
+			return ""
+		}
+		pos, named := templateArgs(rawArgs)
+		arg := func(key string, i int) string {
+			if v, ok := named[key]; ok {
+				return v
+			}
+			if i < len(pos) {
+				return pos[i]
+			}
+			return ""
+		}
+
+		switch name {
+		case "youtube":
+			if id := arg("id", 0); id != "" {
+				return "https://www.youtube.com/watch?v=" + id
+			}
+		case "vimeo":
+			if id := arg("id", 0); id != "" {
+				return "https://vimeo.com/" + id
+			}
+		case "gist":
+			if user, id := arg("user", 0), arg("id", 1); user != "" && id != "" {
+				return "https://gist.github.com/" + user + "/" + id
+			}
+		case "tweet", "x", "twitter":
+			user, id := named["user"], named["id"]
+			if id == "" && len(pos) > 0 {
+				id = pos[len(pos)-1]
+			}
+			if user == "" {
+				user = "i"
+			}
+			if id != "" {
+				return "https://twitter.com/" + user + "/status/" + id
+			}
+		case "figure":
+			if src := arg("src", 0); src != "" {
+				alt := named["alt"]
+				if alt == "" {
+					alt = named["title"]
+				}
+				img := "![" + alt + "](" + src + ")"
+				if caption := named["caption"]; caption != "" {
+					img += "  \n*" + caption + "*"
+				}
+				return img
+			}
+		case "ref", "relref":
+			if target := arg("path", 0); target != "" {
+				return refSlug(target)
+			}
+		}
+		return templatePlaceholder(name, rawArgs)
+	})
+}
+
+// renderLiquid renders the Liquid tags in content that have a Markdown
+// equivalent and replaces the others with a placeholder. Comments and tags
+// controlling the template are removed, and the content of raw blocks is
+// kept as is, as are tags in code blocks and inline code.
+func renderLiquid(content string) string {
+	return outsideCode(content, renderLiquidBlocks)
+}
+
+func renderLiquidBlocks(content string) string {
+	var b strings.Builder
+	for {
+		loc := liquidRawRx.FindStringSubmatchIndex(content)
+		if loc == nil {
+			b.WriteString(renderLiquidTags(content))
+			return b.String()
+		}
+		b.WriteString(renderLiquidTags(content[:loc[0]]))
+		b.WriteString(content[loc[2]:loc[3]])
+		content = content[loc[1]:]
+	}
+}
+
+func renderLiquidTags(content string) string {
+	content = liquidCommentRx.ReplaceAllString(content, "")
+	content = liquidHighlightRx.ReplaceAllStringFunc(content, func(s string) string {
+		m := liquidHighlightRx.FindStringSubmatch(s)
+		return fenceCode(m[1], m[2])
+	})
+	content = liquidTagRx.ReplaceAllStringFunc(content, func(s string) string {
+		m := liquidTagRx.FindStringSubmatch(s)
+		name, rawArgs := m[1], strings.TrimSpace(m[2])
+		if liquidControlTags[name] {
+			return ""
+		}
+		switch name {
+		case "post_url":
+			_, slug := jekyllName(path.Base(rawArgs))
+			return slug
+		case "link":
+			return refSlug(rawArgs)
+		}
+		return templatePlaceholder(name, rawArgs)
+	})
+	return liquidOutputRx.ReplaceAllStringFunc(content, func(s string) string {
+		expr := liquidOutputRx.FindStringSubmatch(s)[1]
+		switch expr {
+		case "site.baseurl", "site.url":
+			// links prefixed with the site URL work as they are without it
+			return ""
+		}
+		if m := liquidURLFilterRx.FindStringSubmatch(expr); m != nil && m[1] == m[3] {
+			return m[2]
+		}
+		return templatePlaceholder(expr, "")
+	})
+}
+
+// templateArgs splits the arguments of a shortcode into positional and named
+// ones, removing their quotes.
+func templateArgs(s string) (pos []string, named map[string]string) {
+	named = map[string]string{}
+	for _, m := range templateArgRx.FindAllStringSubmatch(s, -1) {
+		v := m[2]
+		if len(v) >= 2 && strings.ContainsAny(v[:1], "\"'`") && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		if m[1] != "" {
+			named[m[1]] = v
+		} else {
+			pos = append(pos, v)
+		}
+	}
+	return pos, named
+}
+
+// refSlug returns the slug of the post at the site path target, such as
+// my-post for posts/my-post.md, which links to the post once imported.
+func refSlug(target string) string {
+	target = strings.Trim(target, "\"'` ")
+	base := path.Base(target)
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	if stem == "index" {
+		stem = bundleSlug(target)
+	}
+	_, slug := jekyllName(stem)
+	return slug
+}
+
+// bundleSlug returns the slug of the index file at the site path name, named
+// after the directory it is in, or an empty slug if it is not in one.
+func bundleSlug(name string) string {
+	dir := path.Dir(name)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return path.Base(dir)
+}
+
+// templatePlaceholder returns the readable stand-in for a template tag that
+// cannot be rendered, such as [include footer.html].
+func templatePlaceholder(name, args string) string {
+	return "[" + strings.TrimSpace(name+" "+singleLine(args)) + "]"
+}
+
+// outsideCode returns content passed through render, leaving its Markdown
+// code blocks and inline code as they are. The code is swapped for
+// placeholders while rendering, so that tags around it are still matched.
+func outsideCode(content string, render func(content string) string) string {
+	var code []string
+	hold := func(s string) string {
+		code = append(code, s)
+		return "\x00" + strconv.Itoa(len(code)-1) + "\x00"
+	}
+
+	var b, text strings.Builder
+	flush := func() {
+		b.WriteString(holdCodeSpans(text.String(), hold))
+		text.Reset()
+	}
+	lines := strings.SplitAfter(content, "\n")
+	// blank is set after a line that ends a paragraph, which an indented
+	// code block cannot interrupt, and inList within a list whose indented
+	// lines belong to its items
+	blank, inList := true, false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := codeFenceRx.FindStringSubmatch(line); m != nil {
+			fence := m[1]
+			end := i + 1
+			for end < len(lines) {
+				l := strings.TrimSpace(lines[end])
+				end++
+				if strings.HasPrefix(l, fence) && strings.Trim(l, fence[:1]) == "" {
+					break
+				}
+			}
+			flush()
+			b.WriteString(hold(strings.Join(lines[i:end], "")))
+			i, blank = end-1, true
+			continue
+		}
+		if blank && !inList && indentedCode(line) {
+			end := i + 1
+			for end < len(lines) && (indentedCode(lines[end]) || strings.TrimSpace(lines[end]) == "") {
+				end++
+			}
+			// blank lines after the block are left to the text
+			for strings.TrimSpace(lines[end-1]) == "" {
+				end--
+			}
+			flush()
+			b.WriteString(hold(strings.Join(lines[i:end], "")))
+			i, blank = end-1, false
+			continue
+		}
+
+		text.WriteString(line)
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			continue
+		}
+		if !indentedCode(line) {
+			inList = listItemRx.MatchString(line)
+		}
+		blank = false
+	}
+	flush()
+
+	if len(code) == 0 {
+		return render(content)
+	}
+	return heldCodeRx.ReplaceAllStringFunc(render(b.String()), func(s string) string {
+		i, _ := strconv.Atoi(heldCodeRx.FindStringSubmatch(s)[1])
+		return code[i]
+	})
+}
+
+// indentedCode returns whether line is indented as a line of a Markdown code
+// block.
+func indentedCode(line string) bool {
+	return strings.TrimSpace(line) != "" && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t"))
+}
+
+// holdCodeSpans returns text with its inline code spans passed through hold.
+// A span ends at the next run of as many backticks as it started with, within
+// the same paragraph. Backticks quoting the arguments of a template tag do
+// not start a span.
+func holdCodeSpans(text string, hold func(code string) string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(text, '`')
+		if i < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		eol := len(text)
+		if j := strings.IndexByte(text[i:], '\n'); j >= 0 {
+			eol = i + j
+		}
+		if loc := templateTagRx.FindStringIndex(text[:eol]); loc != nil && loc[0] < i {
+			b.WriteString(text[:loc[1]])
+			text = text[loc[1]:]
+			continue
+		}
+		n := backtickRun(text[i:])
+		rest := text[i+n:]
+		end := -1
+		for j := 0; j < len(rest); {
+			k := strings.IndexByte(rest[j:], '`')
+			if k < 0 {
+				break
+			}
+			j += k
+			m := backtickRun(rest[j:])
+			if m == n {
+				end = j
+				break
+			}
+			j += m
+		}
+		if end < 0 || strings.Contains(rest[:end], "\n\n") {
+			b.WriteString(text[:i+n])
+			text = rest
+			continue
+		}
+		b.WriteString(text[:i])
+		b.WriteString(hold(text[i : i+n+end+n]))
+		text = rest[end+n:]
+	}
+}
+
+// backtickRun returns the number of backticks s starts with.
+func backtickRun(s string) int {
+	n := 0
+	for n < len(s) && s[n] == '`' {
+		n++
+	}
+	return n
+}
+
+// fenceCode returns code as a fenced code block in lang.
+func fenceCode(lang, code string) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.Trim(code, "\n") + "\n" + fence
+}
@@ -0,0 +1,205 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// getTestSite writes files into a new temporary directory and returns its
+// path.
+func getTestSite(t *testing.T, files fileList) string {
+	dir, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	for _, f := range files {
+		fp := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(f.Contents), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestFromStaticSiteHugo(t *testing.T) {
+	dir := getTestSite(t, fileList{
+		{"config.toml", `title = "My Site"`},
+		{"content/_index.md", "---\ntitle: Home\n---\nWelcome."},
+		{"content/about.md", "---\ntitle: About\n---\nAbout me."},
+		{"content/posts/_index.md", "---\ntitle: Posts\n---\n"},
+		{"content/posts/first.md", "---\ntitle: First\ndate: 2019-05-04\n---\nHello.\n\n{{< youtube w7Ft2ymGmfc >}}"},
+		{"content/posts/trip/index.md", "---\ntitle: Trip\n---\n{{< figure src=\"map.png\" alt=\"Map\" >}}\n\n{{% notice info %}}Bring water.{{% /notice %}}\n\n{{< instagram BWNjjyYFxVx hidecaption >}}"},
+		{"content/posts/trip/map.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"},
+		{"content/posts/wip.md", "---\ntitle: WIP\ndraft: true\n---\nNot yet."},
+		{"content/notes/idea.md", "An idea, see [first]({{< ref \"posts/first.md\" >}})."},
+		{"themes/theme/layouts/index.html", "<html>{{ .Content }}</html>"},
+	})
+	defer os.RemoveAll(dir)
+
+	colls, err := FromStaticSite(dir, HugoSite)
+	if err != nil {
+		t.Fatalf("failed to get posts from site: %v", err)
+	}
+
+	posts := colls["posts"]
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts but expected 2", len(posts))
+	}
+	tt := []struct {
+		Slug, Content string
+	}{
+		{"first", "Hello.\n\nhttps://www.youtube.com/watch?v=w7Ft2ymGmfc"},
+		{"trip", "![Map](map.png)\n\nBring water.\n\n[instagram BWNjjyYFxVx hidecaption]"},
+	}
+	for i, tc := range tt {
+		if posts[i].Slug != tc.Slug || posts[i].Content != tc.Content {
+			t.Fatalf("got post with slug %q and content %q but expected %q and %q", posts[i].Slug, posts[i].Content, tc.Slug, tc.Content)
+		}
+		if posts[i].Collection != "posts" {
+			t.Fatalf("got collection %q but expected %q", posts[i].Collection, "posts")
+		}
+	}
+	if len(colls["notes"]) != 1 || colls["notes"][0].Content != "An idea, see [first](first)." {
+		t.Fatalf("got notes %v but expected one linking to first", colls["notes"])
+	}
+
+	drafts := colls[DraftsKey]
+	if len(drafts) != 2 {
+		t.Fatalf("got %d drafts but expected 2", len(drafts))
+	}
+	if drafts[0].Title != "About" || drafts[1].Title != "WIP" {
+		t.Fatalf("got drafts %q and %q but expected About and WIP", drafts[0].Title, drafts[1].Title)
+	}
+}
+
+func TestFromStaticSiteJekyll(t *testing.T) {
+	dir := getTestSite(t, fileList{
+		{"_config.yml", "title: My Site"},
+		{"index.md", "---\nlayout: home\n---\n"},
+		{"_posts/2019-05-04-first-post.md", "---\ntitle: First Post\n---\nHello.\n\n{% highlight ruby %}\nputs 1\n{% endhighlight %}\n\n{% include newsletter.html %}"},
+		{"_posts/2019-06-01-dated.md", "---\ntitle: Dated\ndate: 2019-06-02 10:00:00\n---\n![Pic]({{ site.baseurl }}/img/pic.png) and [more]({% post_url 2019-05-04-first-post %}).\n\n{% raw %}{{ kept }}{% endraw %} {{ page.title }}"},
+		{"_posts/notes.md", "Not a post without a date."},
+		{"_drafts/someday.md", "---\ntitle: Someday\n---\n{% if site.show %}Maybe.{% endif %}"},
+		{"_site/2019/05/04/first-post.html", "<p>Generated.</p>"},
+	})
+	defer os.RemoveAll(dir)
+
+	colls, err := FromStaticSite(dir, JekyllSite)
+	if err != nil {
+		t.Fatalf("failed to get posts from site: %v", err)
+	}
+
+	posts := colls[JekyllKey]
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts but expected 2", len(posts))
+	}
+	p := posts[0]
+	if p.Slug != "first-post" {
+		t.Fatalf("got slug %q but expected %q", p.Slug, "first-post")
+	}
+	expected := "Hello.\n\n```ruby\nputs 1\n```\n\n[include newsletter.html]"
+	if p.Content != expected {
+		t.Fatalf("got content %q but expected %q", p.Content, expected)
+	}
+	created := time.Date(2019, 5, 4, 0, 0, 0, 0, time.UTC)
+	if p.Created == nil || !p.Created.Equal(created) {
+		t.Fatalf("got created %v but expected %v", p.Created, created)
+	}
+
+	p = posts[1]
+	expected = "![Pic](/img/pic.png) and [more](first-post).\n\n{{ kept }} [page.title]"
+	if p.Content != expected {
+		t.Fatalf("got content %q but expected %q", p.Content, expected)
+	}
+	created = time.Date(2019, 6, 2, 10, 0, 0, 0, time.UTC)
+	if p.Created == nil || !p.Created.Equal(created) {
+		t.Fatalf("got created %v but expected the front matter date %v", p.Created, created)
+	}
+
+	drafts := colls[DraftsKey]
+	if len(drafts) != 1 || drafts[0].Slug != "someday" || drafts[0].Content != "Maybe." {
+		t.Fatalf("got drafts %v but expected someday", drafts)
+	}
+}
+
+func TestFromStaticSiteHugoHome(t *testing.T) {
+	dir := getTestSite(t, fileList{
+		{"content/index.md", "---\ntitle: Home\n---\nWelcome, see [about]({{< ref \"index.md\" >}})."},
+	})
+	defer os.RemoveAll(dir)
+
+	colls, err := FromStaticSite(dir, HugoSite)
+	if err != nil {
+		t.Fatalf("failed to get posts from site: %v", err)
+	}
+	drafts := colls[DraftsKey]
+	if len(drafts) != 1 || drafts[0].Slug != "" || drafts[0].Content != "Welcome, see [about]()." {
+		t.Fatalf("got drafts %v but expected the home page without a slug", drafts)
+	}
+}
+
+func TestRenderTemplatesInCode(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Render   func(string) string
+		Content  string
+		Expected string
+	}{
+		{"fenced shortcode", renderShortcodes, "Watch:\n\n```\n{{< youtube abc >}}\n```\n\n{{< youtube abc >}}", "Watch:\n\n```\n{{< youtube abc >}}\n```\n\nhttps://www.youtube.com/watch?v=abc"},
+		{"indented shortcode", renderShortcodes, "Use it as:\n\n    {{< youtube abc >}}\n\nDone.", "Use it as:\n\n    {{< youtube abc >}}\n\nDone."},
+		{"inline shortcode", renderShortcodes, "Write `{{< youtube abc >}}` for {{< youtube abc >}}", "Write `{{< youtube abc >}}` for https://www.youtube.com/watch?v=abc"},
+		{"shortcode around code", renderShortcodes, "{{% notice %}}Run `{{% x %}}`.{{% /notice %}}", "Run `{{% x %}}`."},
+		{"quoted shortcode argument", renderShortcodes, "{{< figure src=\"a.png\" caption=`A map` >}} and `code`", "![](a.png)  \n*A map* and `code`"},
+		{"list item", renderShortcodes, "- Item\n\n    {{< youtube abc >}}", "- Item\n\n    https://www.youtube.com/watch?v=abc"},
+		{"fenced liquid", renderLiquid, "~~~liquid\n{% include footer.html %}\n{{ page.title }}\n~~~\n{% include footer.html %}", "~~~liquid\n{% include footer.html %}\n{{ page.title }}\n~~~\n[include footer.html]"},
+		{"inline liquid", renderLiquid, "Print ``{{ page.title }}`` with {{ page.title }}", "Print ``{{ page.title }}`` with [page.title]"},
+		{"unclosed span", renderLiquid, "A ` and {{ page.title }}", "A ` and [page.title]"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := tc.Render(tc.Content); got != tc.Expected {
+				t.Fatalf("got %q but expected %q", got, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestFromStaticSiteMissing(t *testing.T) {
+	dir := getTestSite(t, fileList{{"index.md", "Hello."}})
+	defer os.RemoveAll(dir)
+
+	if _, err := FromStaticSite(dir, HugoSite); !os.IsNotExist(err) {
+		t.Fatalf("got error %v but expected the content directory not to exist", err)
+	}
+	if _, err := FromStaticSite(dir, SiteKind(-1)); err == nil {
+		t.Fatalf("got no error but expected one for an unknown site kind")
+	}
+}
+
+func TestFromStaticSiteContext(t *testing.T) {
+	dir := getTestSite(t, fileList{{"_posts/2019-05-04-first.md", "Hello."}})
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	colls, err := FromStaticSiteContext(ctx, dir, JekyllSite)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls[JekyllKey]) != 0 {
+		t.Fatalf("got %d posts but expected 0", len(colls[JekyllKey]))
+	}
+}
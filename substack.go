@@ -0,0 +1,200 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+const (
+	// SubstackKey is the key for published Substack posts in a
+	// ZipCollections map.
+	SubstackKey = "substack"
+
+	substackIndex    = "posts.csv"
+	substackPostsDir = "posts/"
+)
+
+// substackPost is the metadata of a post from the posts.csv file of a
+// Substack export.
+type substackPost struct {
+	Date       string
+	Published  bool
+	Title      string
+	Subtitle   string
+	Type       string
+	PodcastURL string
+}
+
+// FromSubstack opens a Substack export archive and returns the posts it
+// contains and an error if any. The posts.csv file of the export is joined
+// with the HTML bodies in its posts directory, which are converted to
+// Markdown with the subtitle of the post as an italic lead.
+//
+// Published posts are keyed by SubstackKey, unpublished ones are included
+// under DraftsKey. Posts are given the slug they had on Substack.
+func FromSubstack(archive string, opts ...Option) (ZipCollections, error) {
+	return fromSubstack(archive, newOptions(opts))
+}
+
+// FromSubstackContext works as FromSubstack, checking ctx between posts and
+// while converting them. Once ctx is done the posts read until then are
+// returned along with ctx.Err().
+func FromSubstackContext(ctx context.Context, archive string, opts ...Option) (ZipCollections, error) {
+	return fromSubstack(archive, contextOptions(ctx, opts))
+}
+
+func fromSubstack(archive string, o *options) (ZipCollections, error) {
+	a, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	var index *zip.File
+	for _, f := range a.File {
+		if f.Name == substackIndex {
+			index = f
+			break
+		}
+	}
+	if index == nil {
+		return nil, fileError(substackIndex, archive, fs.ErrNotExist)
+	}
+	posts, err := readSubstackIndex(index)
+	if err != nil {
+		return nil, fileError(substackIndex, archive, err)
+	}
+
+	out := ZipCollections{DraftsKey: []*writeas.PostParams{}}
+	postErrors, err := walkZipFiles(archive, a.File, substackZipFunc(posts).parser(), func(coll string, p *writeas.PostParams) error {
+		if p == nil {
+			return nil
+		}
+		key := DraftsKey
+		if p.Collection != "" {
+			p.Collection = o.collectionKey(p.Collection)
+			key = p.Collection
+		}
+		out[key] = append(out[key], p)
+		return nil
+	}, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return out, err
+}
+
+// readSubstackIndex reads the posts.csv file f, returning the metadata of
+// each post keyed by its ID, which names the HTML file of its body.
+func readSubstackIndex(f *zip.File) (map[string]substackPost, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	records, err := csv.NewReader(rc).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrEmptyFile
+	}
+	cols := map[string]int{}
+	for i, name := range records[0] {
+		cols[strings.TrimSpace(name)] = i
+	}
+	field := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	posts := map[string]substackPost{}
+	for _, record := range records[1:] {
+		id := field(record, "post_id")
+		if id == "" {
+			continue
+		}
+		posts[id] = substackPost{
+			Date:       field(record, "post_date"),
+			Published:  field(record, "is_published") == "true",
+			Title:      field(record, "title"),
+			Subtitle:   field(record, "subtitle"),
+			Type:       field(record, "type"),
+			PodcastURL: field(record, "podcast_url"),
+		}
+	}
+	return posts, nil
+}
+
+// substackZipFunc returns a ZipContextFunc parsing the HTML post files in the
+// posts directory of a Substack export, taking the title, date and status of
+// each from posts. Files of posts missing from posts are returned as no post.
+func substackZipFunc(posts map[string]substackPost) ZipContextFunc {
+	return func(ctx context.Context, f *zip.File) (*writeas.PostParams, error) {
+		name := f.FileHeader.Name
+		if f.FileInfo().IsDir() || !strings.HasPrefix(name, substackPostsDir) || path.Ext(name) != ".html" {
+			return nil, nil
+		}
+		id := strings.TrimSuffix(path.Base(name), ".html")
+		meta, ok := posts[id]
+		if !ok {
+			return nil, nil
+		}
+		b, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		return meta.post(ctx, id, b)
+	}
+}
+
+// post converts the HTML body b of the post with the given ID into a post.
+func (sp substackPost) post(ctx context.Context, id string, b []byte) (*writeas.PostParams, error) {
+	body, err := htmlStringToMarkdown(ctx, string(b))
+	if err != nil {
+		return nil, err
+	}
+	if sp.Type == "podcast" && sp.PodcastURL != "" {
+		body = strings.TrimSpace("<" + sp.PodcastURL + ">\n\n" + body)
+	}
+	if body == "" {
+		return nil, ErrEmptyFile
+	}
+	if sp.Subtitle != "" {
+		body = "*" + sp.Subtitle + "*\n\n" + body
+	}
+
+	p := &writeas.PostParams{
+		Title:   sp.Title,
+		Content: body,
+	}
+	// post IDs are the numeric ID and slug of the post, as in 1234.my-post
+	if i := strings.IndexByte(id, '.'); i != -1 {
+		p.Slug = id[i+1:]
+	}
+	if t, err := time.Parse(time.RFC3339, sp.Date); err == nil {
+		p.Created = &t
+	}
+	if sp.Published {
+		p.Collection = SubstackKey
+	}
+	return p, nil
+}
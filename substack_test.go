@@ -0,0 +1,94 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+)
+
+const substackCSV = `post_id,post_date,is_published,email_sent_at,inbox_sent_at,type,audience,title,subtitle,podcast_url
+1001.first-issue,2021-02-01T15:00:00.000Z,true,2021-02-01T15:00:00.000Z,,newsletter,everyone,First Issue,"Welcome, readers",
+1002.next-time,,false,,,newsletter,everyone,Next Time,,
+1003.episode-one,2021-03-01T15:00:00.000Z,true,,,podcast,everyone,Episode One,,https://example.com/ep1.mp3
+`
+
+func TestFromSubstack(t *testing.T) {
+	archive := getTestZip(t, fileList{
+		{"posts.csv", substackCSV},
+		{"posts/1001.first-issue.html", `<p>Hello <em>everyone</em>.</p>`},
+		{"posts/1002.next-time.html", `<p>Coming soon.</p>`},
+		{"posts/1003.episode-one.html", `<p>Show notes.</p>`},
+		{"posts/9999.unlisted.html", `<p>Not in the index.</p>`},
+	})
+	defer os.Remove(archive)
+
+	colls, err := FromSubstack(archive)
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	posts := colls[SubstackKey]
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts but expected 2", len(posts))
+	}
+	p := posts[0]
+	if p.Title != "First Issue" || p.Slug != "first-issue" {
+		t.Fatalf("got title %q and slug %q but expected %q and %q", p.Title, p.Slug, "First Issue", "first-issue")
+	}
+	expected := "*Welcome, readers*\n\nHello *everyone*."
+	if p.Content != expected {
+		t.Fatalf("got content %q but expected %q", p.Content, expected)
+	}
+	created := time.Date(2021, 2, 1, 15, 0, 0, 0, time.UTC)
+	if p.Created == nil || !p.Created.Equal(created) {
+		t.Fatalf("got created %v but expected %v", p.Created, created)
+	}
+	expected = "<https://example.com/ep1.mp3>\n\nShow notes."
+	if posts[1].Content != expected {
+		t.Fatalf("got content %q but expected %q", posts[1].Content, expected)
+	}
+
+	drafts := colls[DraftsKey]
+	if len(drafts) != 1 || drafts[0].Title != "Next Time" {
+		t.Fatalf("got drafts %v but expected Next Time", drafts)
+	}
+	if drafts[0].Collection != "" {
+		t.Fatalf("got draft collection %q but expected none", drafts[0].Collection)
+	}
+}
+
+func TestFromSubstackNoIndex(t *testing.T) {
+	archive := getTestZip(t, fileList{{"posts/1001.first-issue.html", `<p>Hello.</p>`}})
+	defer os.Remove(archive)
+
+	_, err := FromSubstack(archive)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got error %v but expected %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestFromSubstackContext(t *testing.T) {
+	archive := getTestZip(t, fileList{
+		{"posts.csv", substackCSV},
+		{"posts/1001.first-issue.html", `<p>Hello.</p>`},
+	})
+	defer os.Remove(archive)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	colls, err := FromSubstackContext(ctx, archive)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls[SubstackKey]) != 0 {
+		t.Fatalf("got %d posts but expected 0", len(colls[SubstackKey]))
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/writeas/go-writeas/v2"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// TarFunc should return a pointer to a writeas.PostParams for any tar entry
+// that meets criteria. It is used in FromTarByFunc and FromTarDirsByFunc to
+// filter the archives files. The contents of the entry are read from r.
+//
+// It is the tar equivalent of ZipFunc, see TopLevelTarFunc for an example.
+type TarFunc func(h *tar.Header, r io.Reader) (*writeas.PostParams, error)
+
+// TarContextFunc is a TarFunc that is passed the context of the import, so
+// that long conversions can stop once it is done by returning ctx.Err(), as
+// a ZipContextFunc is.
+type TarContextFunc func(ctx context.Context, h *tar.Header, r io.Reader) (*writeas.PostParams, error)
+
+// tarParseFunc is a TarContextFunc that also returns what was learned of the
+// entry while parsing it, as a zipParseFunc does.
+type tarParseFunc func(ctx context.Context, h *tar.Header, r io.Reader) (*writeas.PostParams, parseInfo, error)
+
+// withContext returns f as a TarContextFunc ignoring the context.
+func (f TarFunc) withContext() TarContextFunc {
+	return func(_ context.Context, h *tar.Header, r io.Reader) (*writeas.PostParams, error) {
+		return f(h, r)
+	}
+}
+
+// parser returns f as a tarParseFunc, nothing being known of the entries it
+// parses.
+func (f TarContextFunc) parser() tarParseFunc {
+	return func(ctx context.Context, h *tar.Header, r io.Reader) (*writeas.PostParams, parseInfo, error) {
+		p, err := f(ctx, h, r)
+		return p, parseInfo{}, err
+	}
+}
+
+// TopLevelTarFunc returns a pointer to a writeas.PostParams for any parseable
+// regular file in a tar archive. It is the tar equivalent of TopLevelZipFunc.
+func TopLevelTarFunc(h *tar.Header, r io.Reader) (*writeas.PostParams, error) {
+	return TopLevelTarContextFunc(context.Background(), h, r)
+}
+
+// TopLevelTarContextFunc is the TarContextFunc form of TopLevelTarFunc.
+func TopLevelTarContextFunc(ctx context.Context, h *tar.Header, r io.Reader) (*writeas.PostParams, error) {
+	p, _, err := topLevelTarFile(ctx, h, r)
+	return p, err
+}
+
+// topLevelTarFile works as TopLevelTarContextFunc, also returning what was
+// learned of the entry while parsing it.
+func topLevelTarFile(_ context.Context, h *tar.Header, r io.Reader) (*writeas.PostParams, parseInfo, error) {
+	if h.Typeflag == tar.TypeReg {
+		return readAndParse(h, r)
+	}
+	return nil, parseInfo{}, nil
+}
+
+// TextFileTarFunc parses .txt files into PostParams
+func TextFileTarFunc(h *tar.Header, r io.Reader) (*writeas.PostParams, error) {
+	return TextFileTarContextFunc(context.Background(), h, r)
+}
+
+// TextFileTarContextFunc is the TarContextFunc form of TextFileTarFunc.
+func TextFileTarContextFunc(ctx context.Context, h *tar.Header, r io.Reader) (*writeas.PostParams, error) {
+	if h.Typeflag == tar.TypeReg && strings.HasSuffix(h.Name, ".txt") {
+		p, _, err := readAndParse(h, r)
+		return p, err
+	}
+	return nil, nil
+}
+
+func readAndParse(h *tar.Header, r io.Reader) (*writeas.PostParams, parseInfo, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, parseInfo{}, err
+	}
+	return parseArchiveFile(tarEntryName(h), b, h.ModTime)
+}
+
+// FromTar opens a tar archive, optionally compressed with gzip or zstd, and
+// returns a slice of *writeas.PostParams and an error if any.
+//
+// The archive is read as a stream, one entry at a time, so WithWorkers has
+// no effect on tar imports.
+func FromTar(archive string, opts ...Option) ([]*writeas.PostParams, error) {
+	return postsFromTar(archive, topLevelTarFile, newOptions(opts))
+}
+
+// FromTarContext works as FromTar, checking ctx between entries. Once ctx is
+// done the posts read until then are returned along with ctx.Err().
+func FromTarContext(ctx context.Context, archive string, opts ...Option) ([]*writeas.PostParams, error) {
+	return postsFromTar(archive, topLevelTarFile, contextOptions(ctx, opts))
+}
+
+// FromTarByFunc opens a tar archive and filters the contents according to
+// the passed TarFunc. It returns a slice of writeas.PostParams and any error.
+func FromTarByFunc(archive string, f TarFunc, opts ...Option) ([]*writeas.PostParams, error) {
+	return postsFromTar(archive, f.withContext().parser(), newOptions(opts))
+}
+
+// FromTarByFuncContext works as FromTarByFunc, passing ctx to f and checking
+// it between entries. Once ctx is done the posts read until then are
+// returned along with ctx.Err().
+func FromTarByFuncContext(ctx context.Context, archive string, f TarContextFunc, opts ...Option) ([]*writeas.PostParams, error) {
+	return postsFromTar(archive, f.parser(), contextOptions(ctx, opts))
+}
+
+func postsFromTar(archive string, f tarParseFunc, o *options) ([]*writeas.PostParams, error) {
+	posts := []*writeas.PostParams{}
+	postErrors, err := walkTarPosts(archive, f, func(coll string, p *writeas.PostParams) error {
+		if p != nil {
+			posts = append(posts, p)
+		}
+		return nil
+	}, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	if len(posts) > 0 {
+		return posts, err
+	}
+	return nil, err
+}
+
+// FromTarDirs opens a tar archive, optionally compressed with gzip or zstd,
+// and returns a map of post collections and an error if any. The map is
+// built as it is for FromZipDirs.
+func FromTarDirs(archive string, opts ...Option) (ZipCollections, error) {
+	return postsFromTarDirs(archive, topLevelTarFile, newOptions(opts))
+}
+
+// FromTarDirsContext works as FromTarDirs, checking ctx between entries. Once
+// ctx is done the collections read until then are returned along with
+// ctx.Err().
+func FromTarDirsContext(ctx context.Context, archive string, opts ...Option) (ZipCollections, error) {
+	return postsFromTarDirs(archive, topLevelTarFile, contextOptions(ctx, opts))
+}
+
+// FromTarDirsByFunc works as FromTarDirs but filtering files through f.
+func FromTarDirsByFunc(archive string, f TarFunc, opts ...Option) (ZipCollections, error) {
+	return postsFromTarDirs(archive, f.withContext().parser(), newOptions(opts))
+}
+
+// FromTarDirsByFuncContext works as FromTarDirsByFunc, passing ctx to f and
+// checking it between entries. Once ctx is done the collections read until
+// then are returned along with ctx.Err().
+func FromTarDirsByFuncContext(ctx context.Context, archive string, f TarContextFunc, opts ...Option) (ZipCollections, error) {
+	return postsFromTarDirs(archive, f.parser(), contextOptions(ctx, opts))
+}
+
+func postsFromTarDirs(archive string, f tarParseFunc, o *options) (ZipCollections, error) {
+	out := make(ZipCollections)
+	postErrors, err := walkTarPosts(archive, f, out.add, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return out, err
+}
+
+// WalkTar opens a tar archive and calls fn for every post parsed from it by
+// f as the archive is read, without keeping them in memory. The collection
+// passed to fn is the one the post would have in FromTarDirsByFunc.
+func WalkTar(archive string, f TarFunc, fn WalkFunc, opts ...Option) error {
+	postErrors, err := walkTarPosts(archive, f.withContext().parser(), visitPosts(fn), newOptions(opts))
+	if err != nil {
+		return err
+	}
+	return postErrors
+}
+
+// walkTarPosts visits the posts parsed from the archive entries by f, as
+// walkZipFiles does for zip archives.
+func walkTarPosts(archive string, f tarParseFunc, visit visitFunc, o *options) (postErrors, err error) {
+	if err := visit(DraftsKey, nil); err != nil {
+		return nil, walkResult(err)
+	}
+	err = walkTar(archive, func(h *tar.Header, r io.Reader) error {
+		if err := o.ctx.Err(); err != nil {
+			return err
+		}
+		post, info, err := f(o.ctx, h, r)
+		return o.visitArchiveEntry(visit, archive, tarEntryName(h), info, post, err, &postErrors)
+	})
+	return postErrors, walkResult(err)
+}
+
+// walkTar calls fn for every entry of the tar archive, other than
+// directories, with the contents of the entry in r.
+func walkTar(archive string, fn func(h *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if h.Typeflag == tar.TypeDir {
+			continue
+		}
+		if err := fn(h, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// decompress returns a reader for the uncompressed contents of r, detecting
+// gzip and zstd compression from the first bytes. Other content is returned
+// as is.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return io.NopCloser(br), nil
+}
+
+// tarEntryName returns the name of the entry without the leading ./ that tar
+// adds when archiving the current directory.
+func tarEntryName(h *tar.Header) string {
+	return strings.TrimPrefix(h.Name, "./")
+}
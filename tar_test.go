@@ -0,0 +1,170 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/writeas/go-writeas/v2"
+)
+
+var compressions = []struct {
+	Name   string
+	Ext    string
+	Writer func(io.Writer) (io.WriteCloser, error)
+}{
+	{"none", ".tar", func(w io.Writer) (io.WriteCloser, error) { return nopWriteCloser{w}, nil }},
+	{"gzip", ".tar.gz", func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }},
+	{"zstd", ".tar.zst", func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }},
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestFromTar(t *testing.T) {
+	for _, c := range compressions {
+		t.Run(c.Name, func(t *testing.T) {
+			a := getTestTar(t, files, c.Ext, c.Writer)
+			defer os.Remove(a)
+			posts, err := FromTar(a)
+			if err != nil {
+				t.Fatalf("failed to get posts from archive: %v", err)
+			}
+			if len(posts) != len(files) {
+				t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), len(files))
+			}
+
+			posts, err = FromTarByFunc(a, TextFileTarFunc)
+			if err != nil {
+				t.Fatalf("failed to get posts from archive: %v", err)
+			}
+			if len(posts) != 2 {
+				t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), 2)
+			}
+		})
+	}
+}
+
+func TestFromTarContext(t *testing.T) {
+	a := getTestTar(t, filesWDirs, ".tar", compressions[0].Writer)
+	defer os.Remove(a)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	posts, err := FromTarContext(ctx, a)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if posts != nil {
+		t.Fatalf("got %d posts but expected none", len(posts))
+	}
+	colls, err := FromTarDirsContext(ctx, a)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls) != 1 {
+		t.Fatalf("got %d collections but expected only %s", len(colls), DraftsKey)
+	}
+
+	// cancel while parsing, the posts read until then are returned
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	posts, err = FromTarByFuncContext(ctx, a, func(ctx context.Context, h *tar.Header, r io.Reader) (*writeas.PostParams, error) {
+		p, err := TopLevelTarContextFunc(ctx, h, r)
+		cancel()
+		return p, err
+	})
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), 1)
+	}
+
+	colls, err = FromTarDirsByFuncContext(context.Background(), a, TextFileTarContextFunc)
+	if err != nil {
+		t.Fatalf("failed to get posts from archive: %v", err)
+	}
+	if len(colls[DraftsKey]) != 2 || len(colls["blog"]) != 1 || len(colls["notes"]) != 1 {
+		t.Fatalf("got %v but expected the .txt files alone", colls)
+	}
+}
+
+func TestFromTarDirs(t *testing.T) {
+	for _, c := range compressions {
+		t.Run(c.Name, func(t *testing.T) {
+			a := getTestTar(t, filesWDirs, c.Ext, c.Writer)
+			defer os.Remove(a)
+			postMap, err := FromTarDirs(a)
+			if err != nil {
+				t.Fatalf("getting posts from tar: %v", err)
+			}
+			expected, err := FromZipDirs(getTestZip(t, filesWDirs))
+			if err != nil {
+				t.Fatalf("getting posts from zip: %v", err)
+			}
+			if len(postMap) != len(expected) {
+				t.Fatalf("collection count mismatch: got %d, expecting %d", len(postMap), len(expected))
+			}
+			for coll, posts := range expected {
+				if len(postMap[coll]) != len(posts) {
+					t.Fatalf("%s count mismatch: got %d, expecting %d", coll, len(postMap[coll]), len(posts))
+				}
+			}
+		})
+	}
+}
+
+func getTestTar(t *testing.T, files fileList, ext string, compress func(io.Writer) (io.WriteCloser, error)) string {
+	buf := new(bytes.Buffer)
+	cw, err := compress(buf)
+	if err != nil {
+		t.Fatalf("creating compressor: %v", err)
+	}
+	w := tar.NewWriter(cw)
+	for _, file := range files {
+		err := w.WriteHeader(&tar.Header{
+			Name:     "./" + file.Name,
+			Mode:     0644,
+			Size:     int64(len(file.Contents)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatalf("writing tar header: %v", err)
+		}
+		_, err = w.Write([]byte(file.Contents))
+		if err != nil {
+			t.Fatalf("writing file contents: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing tar writer: %v", err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatalf("closing compressor: %v", err)
+	}
+
+	f, err := ioutil.TempFile("", "testTar-*"+ext)
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer f.Close()
+	if _, err := buf.WriteTo(f); err != nil {
+		t.Fatalf("writing temp file: %v", err)
+	}
+	return f.Name()
+}
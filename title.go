@@ -0,0 +1,122 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+// DefaultTitleMaxLength is the longest first line, in characters, that
+// FirstLineTitle takes as a title unless WithTitleMaxLength is used.
+const DefaultTitleMaxLength = 80
+
+// TitleStrategy decides how the title of a post is found when the file does
+// not start with a Markdown heading or set a title in its front matter.
+type TitleStrategy int
+
+const (
+	// HeadingTitle only takes titles from Markdown headings, leaving plain
+	// text posts untitled. It is the default.
+	HeadingTitle TitleStrategy = iota
+	// FirstLineTitle takes the first line as the title if a blank line
+	// follows it and it is no longer than the maximum title length.
+	FirstLineTitle
+	// SetextTitle takes the first line as the title if it is underlined
+	// with = signs, as in a Setext style Markdown heading.
+	SetextTitle
+	// FilenameTitle derives the title from the name of the file, so that
+	// my-first-post.txt is titled My First Post.
+	FilenameTitle
+)
+
+var setextUnderlineRx = regexp.MustCompile(`^=+[ \t]*$`)
+
+// WithTitleStrategy sets how untitled posts are given a title, see
+// TitleStrategy.
+func WithTitleStrategy(s TitleStrategy) Option {
+	return func(o *options) {
+		o.title = s
+	}
+}
+
+// WithTitleMaxLength sets the longest first line FirstLineTitle takes as a
+// title to n characters.
+func WithTitleMaxLength(n int) Option {
+	return func(o *options) {
+		o.titleMaxLen = n
+	}
+}
+
+// applyTitle gives the post parsed from the file at name a title according
+// to the title strategy, if it has none yet.
+func (o *options) applyTitle(name string, p *writeas.PostParams) {
+	if p == nil || p.Title != "" {
+		return
+	}
+	switch o.title {
+	case FirstLineTitle:
+		p.Title, p.Content = firstLineTitle(p.Content, o.titleMaxLen)
+	case SetextTitle:
+		p.Title, p.Content = setextTitle(p.Content)
+	case FilenameTitle:
+		p.Title = filenameTitle(name)
+	}
+}
+
+// firstLineTitle returns the first line of content as the title, and the
+// rest as the body, if it is followed by a blank line and is at most maxLen
+// characters long.
+func firstLineTitle(content string, maxLen int) (title, body string) {
+	lines := strings.SplitN(content, "\n", 3)
+	if len(lines) < 3 || strings.TrimSpace(lines[1]) != "" {
+		return "", content
+	}
+	title = strings.TrimSpace(lines[0])
+	if title == "" || utf8.RuneCountInString(title) > maxLen {
+		return "", content
+	}
+	return title, strings.TrimLeft(lines[2], " \t\r\n")
+}
+
+// setextTitle returns the first line of content as the title, and the rest
+// as the body, if the second line underlines it with = signs.
+func setextTitle(content string) (title, body string) {
+	lines := strings.SplitN(content, "\n", 3)
+	if len(lines) < 2 || !setextUnderlineRx.MatchString(lines[1]) {
+		return "", content
+	}
+	title = strings.TrimSpace(lines[0])
+	if title == "" {
+		return "", content
+	}
+	if len(lines) == 3 {
+		body = strings.TrimLeft(lines[2], " \t\r\n")
+	}
+	return title, body
+}
+
+// filenameTitle turns the file name, without its directory and extension,
+// into a title by splitting it into words on dashes and underscores and
+// capitalizing each of them.
+func filenameTitle(name string) string {
+	base := path.Base(strings.Replace(name, `\`, "/", -1))
+	base = strings.TrimSuffix(base, path.Ext(base))
+	words := strings.FieldsFunc(base, func(r rune) bool {
+		return r == '-' || r == '_' || unicode.IsSpace(r)
+	})
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
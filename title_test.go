@@ -0,0 +1,115 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+func TestApplyTitle(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Strategy TitleStrategy
+		File     string
+		Content  string
+		Title    string
+		Body     string
+	}{
+		{
+			Name:     "heading only",
+			Strategy: HeadingTitle,
+			File:     "post.txt",
+			Content:  "A title\n\nSome text.",
+			Title:    "",
+			Body:     "A title\n\nSome text.",
+		}, {
+			Name:     "first line",
+			Strategy: FirstLineTitle,
+			File:     "post.txt",
+			Content:  "A title\n\nSome text.",
+			Title:    "A title",
+			Body:     "Some text.",
+		}, {
+			Name:     "first line without blank line",
+			Strategy: FirstLineTitle,
+			File:     "post.txt",
+			Content:  "Some text\nthat goes on.",
+			Title:    "",
+			Body:     "Some text\nthat goes on.",
+		}, {
+			Name:     "first line too long",
+			Strategy: FirstLineTitle,
+			File:     "post.txt",
+			Content:  strings.Repeat("long ", 20) + "\n\nSome text.",
+			Title:    "",
+			Body:     strings.Repeat("long ", 20) + "\n\nSome text.",
+		}, {
+			Name:     "setext",
+			Strategy: SetextTitle,
+			File:     "post.txt",
+			Content:  "A title\n=======\n\nSome text.",
+			Title:    "A title",
+			Body:     "Some text.",
+		}, {
+			Name:     "setext without underline",
+			Strategy: SetextTitle,
+			File:     "post.txt",
+			Content:  "A title\n\nSome text.",
+			Title:    "",
+			Body:     "A title\n\nSome text.",
+		}, {
+			Name:     "filename",
+			Strategy: FilenameTitle,
+			File:     "blog/my-first_post.txt",
+			Content:  "Some text.",
+			Title:    "My First Post",
+			Body:     "Some text.",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			p := &writeas.PostParams{Content: tc.Content}
+			newOptions([]Option{WithTitleStrategy(tc.Strategy)}).applyTitle(tc.File, p)
+			if p.Title != tc.Title {
+				t.Fatalf("got title %q but expected %q", p.Title, tc.Title)
+			}
+			if p.Content != tc.Body {
+				t.Fatalf("got body %q but expected %q", p.Content, tc.Body)
+			}
+		})
+	}
+}
+
+func TestTitleStrategyImport(t *testing.T) {
+	a := getTestZip(t, fileList{
+		{"notes.txt", "Shopping list\n\nEggs and milk."},
+		{"books.md", "# Title of post\n\ntext body of post."},
+	})
+
+	posts, err := FromZip(a, WithTitleStrategy(FirstLineTitle), WithTitleMaxLength(10))
+	if err != nil {
+		t.Fatalf("failed to get posts from archive: %v", err)
+	}
+	if posts[0].Title != "" {
+		t.Fatalf("got title %q but expected none, it is over the maximum length", posts[0].Title)
+	}
+	if posts[1].Title != "Title of post" {
+		t.Fatalf("got title %q but expected %q", posts[1].Title, "Title of post")
+	}
+
+	posts, err = FromZip(a, WithTitleStrategy(FirstLineTitle))
+	if err != nil {
+		t.Fatalf("failed to get posts from archive: %v", err)
+	}
+	if posts[0].Title != "Shopping list" {
+		t.Fatalf("got title %q but expected %q", posts[0].Title, "Shopping list")
+	}
+}
@@ -0,0 +1,420 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// TumblrKey is the key for published Tumblr posts in a ZipCollections
+	// map when the export does not name the blog they are from.
+	TumblrKey = "tumblr"
+
+	tumblrPostsXML       = "posts.xml"
+	tumblrDateLayout     = "2006-01-02 15:04:05 MST"
+	tumblrHTMLDateLayout = "January 2, 2006 3:04pm"
+)
+
+// tumblrOrdinalRx matches the suffix of the day in the dates of the HTML
+// export, as in January 2nd.
+var tumblrOrdinalRx = regexp.MustCompile(`(\d)(st|nd|rd|th)\b`)
+
+// tumblrPost is a single post from the posts.xml file of an older Tumblr
+// export. Which of the fields are set depends on the type of the post.
+type tumblrPost struct {
+	ID            string `xml:"id,attr"`
+	Type          string `xml:"type,attr"`
+	Slug          string `xml:"slug,attr"`
+	DateGMT       string `xml:"date-gmt,attr"`
+	Tumblelog     string `xml:"tumblelog,attr"`
+	Private       string `xml:"private,attr"`
+	IsReblog      string `xml:"is-reblog,attr"`
+	RebloggedFrom string `xml:"reblogged-from-url,attr"`
+
+	Tags []string `xml:"tag"`
+
+	RegularTitle      string           `xml:"regular-title"`
+	RegularBody       string           `xml:"regular-body"`
+	QuoteText         string           `xml:"quote-text"`
+	QuoteSource       string           `xml:"quote-source"`
+	LinkText          string           `xml:"link-text"`
+	LinkURL           string           `xml:"link-url"`
+	LinkDescription   string           `xml:"link-description"`
+	PhotoCaption      string           `xml:"photo-caption"`
+	PhotoURLs         []tumblrPhotoURL `xml:"photo-url"`
+	Photoset          []tumblrPhoto    `xml:"photoset>photo"`
+	ConversationTitle string           `xml:"conversation-title"`
+	Conversation      []tumblrLine     `xml:"conversation>line"`
+	VideoCaption      string           `xml:"video-caption"`
+	AudioCaption      string           `xml:"audio-caption"`
+	Question          string           `xml:"question"`
+	Answer            string           `xml:"answer"`
+}
+
+// tumblrPhotoURL is one of the sizes a photo is available in.
+type tumblrPhotoURL struct {
+	MaxWidth int    `xml:"max-width,attr"`
+	URL      string `xml:",chardata"`
+}
+
+type tumblrPhoto struct {
+	Caption string           `xml:"caption,attr"`
+	URLs    []tumblrPhotoURL `xml:"photo-url"`
+}
+
+type tumblrLine struct {
+	Label string `xml:"label,attr"`
+	Text  string `xml:",chardata"`
+}
+
+// WithReblogs includes the posts reblogged from other blogs in a Tumblr
+// import, which are left out by default.
+func WithReblogs() Option {
+	return func(o *options) {
+		o.reblogs = true
+	}
+}
+
+// FromTumblr opens a Tumblr export archive and returns the posts it contains
+// and an error if any. Older exports hold all posts in a posts.xml file,
+// newer ones have an HTML file for each post in posts/html.
+//
+// Text, quote, link, photo, chat, video, audio and answer posts are rendered
+// as Markdown with their tags appended as hashtags. Posts are keyed by the
+// name of the blog, or TumblrKey if the export does not give it, and private
+// posts are included under DraftsKey. Reblogs are left out unless
+// WithReblogs is given. Posts that fail to convert are handled as the
+// ErrorPolicy says, and reported as posts.xml#<id> for older exports.
+func FromTumblr(archive string, opts ...Option) (ZipCollections, error) {
+	return fromTumblr(archive, newOptions(opts))
+}
+
+// FromTumblrContext works as FromTumblr, checking ctx between posts and while
+// converting them. Once ctx is done the posts read until then are returned
+// along with ctx.Err().
+func FromTumblrContext(ctx context.Context, archive string, opts ...Option) (ZipCollections, error) {
+	return fromTumblr(archive, contextOptions(ctx, opts))
+}
+
+func fromTumblr(archive string, o *options) (ZipCollections, error) {
+	a, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	out := ZipCollections{DraftsKey: []*writeas.PostParams{}}
+	for _, f := range a.File {
+		if path.Base(f.Name) != tumblrPostsXML {
+			continue
+		}
+		postErrors, err := fromTumblrXML(archive, f, out, o)
+		if err != nil && !canceled(o.ctx, err) {
+			return nil, err
+		} else if err == nil {
+			err = postErrors
+		}
+		return out, err
+	}
+
+	postErrors, err := walkZipFiles(archive, a.File, tumblrZipFunc(o.reblogs).parser(), func(coll string, p *writeas.PostParams) error {
+		if p != nil {
+			p.Collection = o.collectionKey(p.Collection)
+			out[p.Collection] = append(out[p.Collection], p)
+		}
+		return nil
+	}, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return out, err
+}
+
+// fromTumblrXML adds the posts in the posts.xml file f of archive to out.
+// Errors converting single posts, or reading the file past the last
+// complete one, are gathered into postErrors as the error policy allows,
+// while err is set if the import was aborted.
+func fromTumblrXML(archive string, f *zip.File, out ZipCollections, o *options) (postErrors, err error) {
+	// failed handles err for the post id, or for the whole file if id is
+	// empty
+	failed := func(id string, err error) error {
+		if id != "" {
+			o.recordPost(f.Name, id, nil, err)
+			err = fmt.Errorf("post %s: %w", id, err)
+		} else {
+			o.record(f.Name, "", nil, err)
+		}
+		return o.fileFailed(&postErrors, fileError(f.Name, archive, err))
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		if err := failed("", err); err != nil {
+			return nil, err
+		}
+		return postErrors, nil
+	}
+	defer rc.Close()
+
+	d := xml.NewDecoder(rc)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	n := 0
+	for {
+		if err := o.ctx.Err(); err != nil {
+			return postErrors, err
+		}
+		tok, err := d.Token()
+		if err == io.EOF {
+			return postErrors, nil
+		} else if err != nil {
+			// the rest of the file cannot be read, the posts before it are
+			// kept as the error policy allows
+			if err := failed("", err); err != nil {
+				return nil, err
+			}
+			return postErrors, nil
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "post" {
+			continue
+		}
+
+		n++
+		tp := tumblrPost{}
+		if err := d.DecodeElement(&tp, &se); err != nil {
+			if err := failed(strconv.Itoa(n), err); err != nil {
+				return nil, err
+			}
+			return postErrors, nil
+		}
+		if !o.reblogs && (tp.IsReblog == "true" || tp.RebloggedFrom != "") {
+			continue
+		}
+		id := tp.ID
+		if id == "" {
+			id = strconv.Itoa(n)
+		}
+		p, err := tp.post(o.ctx)
+		if canceled(o.ctx, err) {
+			return postErrors, err
+		} else if err != nil {
+			if err := failed(id, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if p == nil {
+			o.recordPost(f.Name, id, nil, ErrEmptyFile)
+			continue
+		}
+		o.recordPost(f.Name, id, p, nil)
+
+		if tp.Private == "true" {
+			out[DraftsKey] = append(out[DraftsKey], p)
+			continue
+		}
+		key := tp.Tumblelog
+		if key == "" {
+			key = TumblrKey
+		}
+		key = o.collectionKey(key)
+		p.Collection = key
+		out[key] = append(out[key], p)
+	}
+}
+
+// post renders the post as Markdown according to its type, or returns nil
+// if it has no content.
+func (tp tumblrPost) post(ctx context.Context) (*writeas.PostParams, error) {
+	var (
+		title string
+		parts []string
+	)
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	// md converts the HTML fragments the post fields are made of, keeping
+	// the first error to return it once the post is rendered
+	var mdErr error
+	md := func(s string) string {
+		if s == "" || mdErr != nil {
+			return ""
+		}
+		out, err := htmlStringToMarkdown(ctx, s)
+		if err != nil {
+			mdErr = err
+			return ""
+		}
+		return out
+	}
+
+	switch tp.Type {
+	case "regular":
+		title = tp.RegularTitle
+		add(md(tp.RegularBody))
+	case "quote":
+		add(prefixLines(md(tp.QuoteText), "> "))
+		if src := md(tp.QuoteSource); src != "" {
+			add("— " + src)
+		}
+	case "link":
+		title = tp.LinkText
+		if tp.LinkURL != "" {
+			add("<" + tp.LinkURL + ">")
+		}
+		add(md(tp.LinkDescription))
+	case "photo":
+		if len(tp.Photoset) > 0 {
+			for _, photo := range tp.Photoset {
+				add(tumblrImage(photo.URLs, photo.Caption))
+			}
+		} else {
+			add(tumblrImage(tp.PhotoURLs, ""))
+		}
+		add(md(tp.PhotoCaption))
+	case "conversation":
+		title = tp.ConversationTitle
+		var lines []string
+		for _, l := range tp.Conversation {
+			line := strings.TrimSpace(l.Text)
+			if l.Label != "" {
+				line = "**" + strings.TrimSpace(l.Label) + "** " + line
+			}
+			lines = append(lines, line)
+		}
+		add(strings.Join(lines, "  \n"))
+	case "video":
+		add(md(tp.VideoCaption))
+	case "audio":
+		add(md(tp.AudioCaption))
+	case "answer":
+		add(prefixLines(singleLine(tp.Question), "> "))
+		add(md(tp.Answer))
+	}
+	if mdErr != nil {
+		return nil, mdErr
+	}
+	add(hashtags(tp.Tags))
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	p := &writeas.PostParams{
+		Title:   strings.TrimSpace(title),
+		Slug:    tp.Slug,
+		Content: strings.Join(parts, "\n\n"),
+	}
+	if t, err := time.Parse(tumblrDateLayout, tp.DateGMT); err == nil {
+		p.Created = &t
+	}
+	return p, nil
+}
+
+// tumblrImage returns a Markdown image of the largest size of a photo.
+func tumblrImage(urls []tumblrPhotoURL, alt string) string {
+	var largest tumblrPhotoURL
+	for _, u := range urls {
+		if u.MaxWidth >= largest.MaxWidth {
+			largest = u
+		}
+	}
+	src := strings.TrimSpace(largest.URL)
+	if src == "" {
+		return ""
+	}
+	return "![" + textEscaper.Replace(singleLine(alt)) + "](" + urlEscaper.Replace(src) + ")"
+}
+
+// tumblrZipFunc returns a ZipContextFunc parsing the HTML post files in the
+// posts/html directory of a newer Tumblr export, which are rendered by type
+// already. Reblogs are returned as no post unless reblogs is set.
+func tumblrZipFunc(reblogs bool) ZipContextFunc {
+	return func(ctx context.Context, f *zip.File) (*writeas.PostParams, error) {
+		name := f.FileHeader.Name
+		if f.FileInfo().IsDir() || path.Base(path.Dir(name)) != "html" || path.Ext(name) != ".html" {
+			return nil, nil
+		}
+		b, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		return parseTumblrPost(ctx, b, reblogs)
+	}
+}
+
+func parseTumblrPost(ctx context.Context, b []byte, reblogs bool) (*writeas.PostParams, error) {
+	if len(b) == 0 {
+		return nil, ErrEmptyFile
+	}
+	doc, err := html.Parse(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if !reblogs && findNode(doc, classMatch("tumblr_blog")) != nil {
+		return nil, nil
+	}
+
+	p := &writeas.PostParams{Collection: TumblrKey}
+	footer := findNode(doc, idMatch("footer"))
+	h1 := findNode(doc, atomMatch(atom.H1))
+	if h1 != nil {
+		p.Title = singleLine(textContent(h1))
+	}
+
+	body := findNode(doc, atomMatch(atom.Body))
+	if body == nil {
+		body = doc
+	}
+	p.Content, err = htmlToMarkdownContext(ctx, body, func(n *html.Node) bool {
+		return n == h1 || n == footer
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if footer != nil {
+		if n := findNode(footer, idMatch("timestamp")); n != nil {
+			date := tumblrOrdinalRx.ReplaceAllString(singleLine(textContent(n)), "$1")
+			if t, err := time.Parse(tumblrHTMLDateLayout, date); err == nil {
+				p.Created = &t
+			}
+		}
+		var tags []string
+		for c := footer.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && hasClass(c, "tag") {
+				tags = append(tags, singleLine(textContent(c)))
+			}
+		}
+		if ht := hashtags(tags); ht != "" {
+			p.Content = strings.TrimSpace(p.Content + "\n\n" + ht)
+		}
+	}
+	if p.Content == "" {
+		return nil, ErrEmptyFile
+	}
+	return p, nil
+}
@@ -0,0 +1,193 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const tumblrXML = `<?xml version="1.0" encoding="UTF-8"?>
+<tumblr version="1.0"><posts>
+<post id="1" type="regular" slug="hello-world" date-gmt="2012-01-02 03:04:05 GMT" tumblelog="dreamer" private="false">
+	<regular-title>Hello World</regular-title>
+	<regular-body>&lt;p&gt;My &lt;i&gt;first&lt;/i&gt; post.&lt;/p&gt;</regular-body>
+	<tag>intro</tag>
+</post>
+<post id="2" type="quote" slug="wise" date-gmt="2012-01-03 03:04:05 GMT" tumblelog="dreamer">
+	<quote-text>Be kind.</quote-text>
+	<quote-source>&lt;a href="https://example.com"&gt;Someone&lt;/a&gt;</quote-source>
+</post>
+<post id="3" type="link" date-gmt="2012-01-04 03:04:05 GMT" tumblelog="dreamer">
+	<link-text>A site</link-text>
+	<link-url>https://example.com</link-url>
+	<link-description>&lt;p&gt;Worth a visit.&lt;/p&gt;</link-description>
+</post>
+<post id="4" type="photo" date-gmt="2012-01-05 03:04:05 GMT" tumblelog="dreamer">
+	<photo-caption>&lt;p&gt;Sunset.&lt;/p&gt;</photo-caption>
+	<photo-url max-width="1280">https://media.example.com/1280.jpg</photo-url>
+	<photo-url max-width="500">https://media.example.com/500.jpg</photo-url>
+</post>
+<post id="5" type="conversation" date-gmt="2012-01-06 03:04:05 GMT" tumblelog="dreamer">
+	<conversation-title>Overheard</conversation-title>
+	<conversation>
+		<line name="a" label="A:">Coffee?</line>
+		<line name="b" label="B:">Always.</line>
+	</conversation>
+</post>
+<post id="6" type="regular" date-gmt="2012-01-07 03:04:05 GMT" tumblelog="dreamer" reblogged-from-url="https://other.tumblr.com/post/9">
+	<regular-body>&lt;p&gt;Not mine.&lt;/p&gt;</regular-body>
+</post>
+<post id="7" type="regular" date-gmt="2012-01-08 03:04:05 GMT" tumblelog="dreamer" private="true">
+	<regular-body>&lt;p&gt;Secret.&lt;/p&gt;</regular-body>
+</post>
+</posts></tumblr>`
+
+func TestFromTumblrXML(t *testing.T) {
+	archive := getTestZip(t, fileList{{"posts.xml", tumblrXML}})
+	defer os.Remove(archive)
+
+	colls, err := FromTumblr(archive)
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	posts := colls["dreamer"]
+	tt := []struct {
+		Title, Slug, Content string
+	}{
+		{"Hello World", "hello-world", "My *first* post.\n\n#Intro"},
+		{"", "wise", "> Be kind.\n\n— [Someone](https://example.com)"},
+		{"A site", "", "<https://example.com>\n\nWorth a visit."},
+		{"", "", "![](https://media.example.com/1280.jpg)\n\nSunset."},
+		{"Overheard", "", "**A:** Coffee?  \n**B:** Always."},
+	}
+	if len(posts) != len(tt) {
+		t.Fatalf("got %d posts but expected %d", len(posts), len(tt))
+	}
+	for i, tc := range tt {
+		p := posts[i]
+		if p.Title != tc.Title || p.Slug != tc.Slug || p.Content != tc.Content {
+			t.Fatalf("got post %d titled %q with slug %q and content %q but expected %q, %q and %q",
+				i, p.Title, p.Slug, p.Content, tc.Title, tc.Slug, tc.Content)
+		}
+	}
+	created := time.Date(2012, 1, 2, 3, 4, 5, 0, time.UTC)
+	if posts[0].Created == nil || !posts[0].Created.Equal(created) {
+		t.Fatalf("got created %v but expected %v", posts[0].Created, created)
+	}
+	if len(colls[DraftsKey]) != 1 {
+		t.Fatalf("got %d drafts but expected 1", len(colls[DraftsKey]))
+	}
+
+	colls, err = FromTumblr(archive, WithReblogs())
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	if len(colls["dreamer"]) != len(tt)+1 {
+		t.Fatalf("got %d posts with reblogs but expected %d", len(colls["dreamer"]), len(tt)+1)
+	}
+}
+
+func TestFromTumblrXMLErrors(t *testing.T) {
+	archive := getTestZip(t, fileList{{"posts.xml", tumblrXML[:strings.Index(tumblrXML, "Sunset.")]}})
+	defer os.Remove(archive)
+
+	r := &Report{}
+	colls, err := FromTumblr(archive, WithReport(r))
+	merr, ok := err.(*multierror.Error)
+	if !ok || len(merr.Errors) != 1 {
+		t.Fatalf("got error %v but expected one collected error", err)
+	}
+	var ferr *FileError
+	if !errors.As(err, &ferr) || ferr.Path != "posts.xml" {
+		t.Fatalf("got error %v but expected a FileError for posts.xml", err)
+	}
+	if len(colls["dreamer"]) != 3 {
+		t.Fatalf("got %d posts but expected the 3 before the failing one", len(colls["dreamer"]))
+	}
+	if len(r.Files) != 4 || r.Files[0].Path != "posts.xml#1" || r.Files[0].Outcome != Imported || r.Files[3].Path != "posts.xml#4" || r.Files[3].Outcome != Failed {
+		t.Fatalf("got report %+v but expected 3 imported posts and a failed one", r.Files)
+	}
+
+	colls, err = FromTumblr(archive, WithErrorPolicy(FailFast))
+	if err == nil || colls != nil {
+		t.Fatalf("got %v and error %v but expected to fail", colls, err)
+	}
+
+	colls, err = FromTumblr(archive, WithErrorPolicy(SkipSilently))
+	if err != nil || len(colls["dreamer"]) != 3 {
+		t.Fatalf("got %v and error %v but expected the 3 complete posts", colls, err)
+	}
+}
+
+func TestTumblrImage(t *testing.T) {
+	urls := []tumblrPhotoURL{{MaxWidth: 500, URL: "https://media.example.com/a (1).jpg"}}
+	got := tumblrImage(urls, "[old] photo")
+	expected := `![\[old\] photo](https://media.example.com/a%20\(1\).jpg)`
+	if got != expected {
+		t.Fatalf("got %q but expected %q", got, expected)
+	}
+}
+
+func TestFromTumblrHTML(t *testing.T) {
+	archive := getTestZip(t, fileList{
+		{"posts/html/101.html", `<!DOCTYPE HTML><html><head><link rel="stylesheet" type="text/css" href="../../style.css"></head><body><h1>Road Trip</h1><p>We drove <b>far</b>.</p><div id="footer"><span id="timestamp"> March 3rd, 2018 4:05pm </span><span class="tag">travel</span><span class="tag">cars</span></div></body></html>`},
+		{"posts/html/102.html", `<!DOCTYPE HTML><html><body><p><a class="tumblr_blog" href="https://other.tumblr.com/post/1">other</a>:</p><blockquote><p>Their words.</p></blockquote><div id="footer"><span id="timestamp"> March 4th, 2018 4:05pm </span></div></body></html>`},
+		{"posts_index.html", `<html><body><a href="posts/html/101.html">101</a></body></html>`},
+	})
+	defer os.Remove(archive)
+
+	colls, err := FromTumblr(archive)
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	posts := colls[TumblrKey]
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts but expected 1", len(posts))
+	}
+	p := posts[0]
+	if p.Title != "Road Trip" {
+		t.Fatalf("got title %q but expected %q", p.Title, "Road Trip")
+	}
+	expected := "We drove **far**.\n\n#Travel #Cars"
+	if p.Content != expected {
+		t.Fatalf("got content %q but expected %q", p.Content, expected)
+	}
+	created := time.Date(2018, 3, 3, 16, 5, 0, 0, time.UTC)
+	if p.Created == nil || !p.Created.Equal(created) {
+		t.Fatalf("got created %v but expected %v", p.Created, created)
+	}
+
+	colls, err = FromTumblr(archive, WithReblogs())
+	if err != nil {
+		t.Fatalf("failed to get posts from export: %v", err)
+	}
+	if len(colls[TumblrKey]) != 2 {
+		t.Fatalf("got %d posts with reblogs but expected 2", len(colls[TumblrKey]))
+	}
+}
+
+func TestFromTumblrContext(t *testing.T) {
+	archive := getTestZip(t, fileList{{"posts.xml", tumblrXML}})
+	defer os.Remove(archive)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	colls, err := FromTumblrContext(ctx, archive)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls["dreamer"]) != 0 {
+		t.Fatalf("got %d posts but expected 0", len(colls["dreamer"]))
+	}
+}
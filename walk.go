@@ -0,0 +1,110 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"errors"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+// WalkFunc is called by WalkFS, WalkZip and WalkTar for each post as soon as
+// it is parsed, with coll being the key the post would have in a
+// ZipCollections map. Returning ErrStopWalk stops the walk without error,
+// any other error stops the walk and is returned by it.
+type WalkFunc func(coll string, p *writeas.PostParams) error
+
+// visitFunc is the internal form of WalkFunc. It is also called with a nil
+// post for every collection as it is first seen, so that collections with no
+// posts are still reported.
+type visitFunc func(coll string, p *writeas.PostParams) error
+
+// visitPosts returns a visitFunc calling fn for posts only.
+func visitPosts(fn WalkFunc) visitFunc {
+	return func(coll string, p *writeas.PostParams) error {
+		if p == nil {
+			return nil
+		}
+		return fn(coll, p)
+	}
+}
+
+// add is a visitFunc collecting posts into c.
+func (c ZipCollections) add(coll string, p *writeas.PostParams) error {
+	if c[coll] == nil {
+		c[coll] = []*writeas.PostParams{}
+	}
+	if p != nil {
+		c[coll] = append(c[coll], p)
+	}
+	return nil
+}
+
+// pathColl returns the collection for the file at the slash separated
+// path name, relative to the root of an import: the first level directory
+// it is in or DraftsKey at the top level.
+func pathColl(name string) string {
+	if _, _, coll := filenameParts(name); coll != "" {
+		return coll
+	}
+	return DraftsKey
+}
+
+// visitArchivePost visits the post parsed from the file name under coll,
+// first visiting the collection of the directory of name so it is reported
+// even if the file is skipped or a draft. Empty and binary files are
+// skipped, other errors are returned.
+func visitArchivePost(visit visitFunc, name, coll string, post *writeas.PostParams, err error) error {
+	if dir := pathColl(name); dir != DraftsKey {
+		if err := visit(dir, nil); err != nil {
+			return err
+		}
+	}
+	if skipped(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if post == nil {
+		return nil
+	}
+	return visit(coll, post)
+}
+
+// skipped reports whether err is the error for an empty or binary file,
+// which imports skip.
+func skipped(err error) bool {
+	return errors.Is(err, ErrEmptyFile) || errors.Is(err, ErrInvalidContentType)
+}
+
+// visitArchiveEntry records the result of parsing the archive entry name,
+// with info learned while parsing it, and visits its post. A failure to
+// parse it is handled as the error policy of o says, gathering it into
+// postErrors if the walk should carry on.
+func (o *options) visitArchiveEntry(visit visitFunc, archive, name string, info parseInfo, post *writeas.PostParams, err error, postErrors *error) error {
+	if post != nil || err != nil {
+		o.record(name, info.enc, post, err)
+	}
+	if err != nil && !skipped(err) {
+		err = o.fileFailed(postErrors, fileError(name, archive, err))
+	}
+	o.applyTitle(name, post)
+	coll := pathColl(name)
+	if info.draft {
+		coll = DraftsKey
+	}
+	return visitArchivePost(visit, name, coll, post, err)
+}
+
+// walkResult returns the error a walk should end with after being stopped
+// by err.
+func walkResult(err error) error {
+	if errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
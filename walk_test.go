@@ -0,0 +1,82 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+func TestWalkZip(t *testing.T) {
+	a := getTestZip(t, filesWDirs)
+	expected, err := FromZipDirs(a)
+	if err != nil {
+		t.Fatalf("getting posts from zip: %v", err)
+	}
+
+	counts := map[string]int{}
+	err = WalkZip(a, TopLevelZipFunc, func(coll string, p *writeas.PostParams) error {
+		if p == nil {
+			t.Fatal("walk func called with nil post")
+		}
+		counts[coll]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking zip: %v", err)
+	}
+	for coll, posts := range expected {
+		if counts[coll] != len(posts) {
+			t.Fatalf("%s count mismatch: got %d, expecting %d", coll, counts[coll], len(posts))
+		}
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	a := getTestZip(t, filesWDirs)
+	calls := 0
+	err := WalkZip(a, TopLevelZipFunc, func(coll string, p *writeas.PostParams) error {
+		calls++
+		if calls == 2 {
+			return ErrStopWalk
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk returned error after stopping: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("walk did not stop: got %d calls, expecting 2", calls)
+	}
+
+	walkErr := errors.New("upload failed")
+	tarArchive := getTestTar(t, filesWDirs, ".tar", compressions[0].Writer)
+	defer os.Remove(tarArchive)
+	err = WalkTar(tarArchive, TopLevelTarFunc, func(coll string, p *writeas.PostParams) error {
+		return walkErr
+	})
+	if err != walkErr {
+		t.Fatalf("got error %v but expected %v", err, walkErr)
+	}
+
+	fsys := fstest.MapFS{
+		"a.md":      {Data: []byte("first")},
+		"blog/b.md": {Data: []byte("second")},
+	}
+	calls = 0
+	err = WalkFS(fsys, ".", func(coll string, p *writeas.PostParams) error {
+		calls++
+		return ErrStopWalk
+	})
+	if err != nil || calls != 1 {
+		t.Fatalf("got error %v after %d calls, expecting nil after 1", err, calls)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+const (
+	// WordPressKey is the key for published WordPress posts in a
+	// ZipCollections map when the export does not name their author.
+	WordPressKey = "wordpress"
+
+	wpDateLayout = "2006-01-02 15:04:05"
+	wpZeroDate   = "0000-00-00 00:00:00"
+)
+
+// wpBlockTagRx matches the start of HTML that wpautop leaves alone, content
+// that contains none of these was written with the classic editor and relies
+// on line breaks for paragraphs.
+var wpBlockTagRx = regexp.MustCompile(`(?i)<(p|div|h[1-6]|ul|ol|li|blockquote|pre|table|figure)[\s>]|<!-- wp:`)
+
+var wpParagraphRx = regexp.MustCompile(`\n\s*\n`)
+
+// wpItem is a single item from the channel of a WordPress eXtended RSS file.
+type wpItem struct {
+	Title       string `xml:"title"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostName    string `xml:"post_name"`
+	PostType    string `xml:"post_type"`
+	Status      string `xml:"status"`
+	PostDate    string `xml:"post_date"`
+	PostDateGMT string `xml:"post_date_gmt"`
+	Modified    string `xml:"post_modified"`
+	ModifiedGMT string `xml:"post_modified_gmt"`
+	Password    string `xml:"post_password"`
+}
+
+// FromWordPress reads a WordPress eXtended RSS (WXR) export from wxrPath and
+// returns the posts it contains and an error if any. Pages, attachments and
+// other post types are left out, see FromWordPressTypes to include them.
+//
+// Published posts are keyed by the login of their author, draft, pending,
+// private and password protected posts are included under DraftsKey. The
+// export is read as a stream so large files are not loaded into memory at
+// once.
+func FromWordPress(wxrPath string, opts ...Option) (ZipCollections, error) {
+	return fromWordPress(wxrPath, []string{"post"}, newOptions(opts))
+}
+
+// FromWordPressTypes works as FromWordPress but includes items of any of the
+// given post types, such as post and page.
+func FromWordPressTypes(wxrPath string, postTypes []string, opts ...Option) (ZipCollections, error) {
+	return fromWordPress(wxrPath, postTypes, newOptions(opts))
+}
+
+// FromWordPressContext works as FromWordPress, checking ctx between items and
+// while converting them. Once ctx is done the posts read until then are
+// returned along with ctx.Err().
+func FromWordPressContext(ctx context.Context, wxrPath string, opts ...Option) (ZipCollections, error) {
+	return fromWordPress(wxrPath, []string{"post"}, contextOptions(ctx, opts))
+}
+
+// FromWordPressTypesContext works as FromWordPressTypes, stopping once ctx is
+// done as FromWordPressContext does.
+func FromWordPressTypesContext(ctx context.Context, wxrPath string, postTypes []string, opts ...Option) (ZipCollections, error) {
+	return fromWordPress(wxrPath, postTypes, contextOptions(ctx, opts))
+}
+
+func fromWordPress(wxrPath string, postTypes []string, o *options) (ZipCollections, error) {
+	f, err := os.Open(wxrPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	colls, postErrors, err := fromWXR(f, wxrPath, postTypes, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return colls, err
+}
+
+// fromWXR reads the posts of the given types from the WXR export at path in
+// r. Errors converting single items are gathered into postErrors, as the
+// error policy allows, while err is set if the import was aborted.
+func fromWXR(r io.Reader, path string, postTypes []string, o *options) (out ZipCollections, postErrors, err error) {
+	types := map[string]bool{}
+	for _, t := range postTypes {
+		types[t] = true
+	}
+
+	var lang string
+	out = ZipCollections{DraftsKey: []*writeas.PostParams{}}
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	for {
+		if err := o.ctx.Err(); err != nil {
+			return out, postErrors, err
+		}
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fileError(path, "", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch se.Name.Local {
+		case "language":
+			// only the channel language is seen here, items are decoded whole
+			var l string
+			if err := d.DecodeElement(&l, &se); err != nil {
+				return nil, nil, fileError(path, "", err)
+			}
+			if l = wpLanguage(l); l != "" {
+				lang = l
+			}
+		case "item":
+			item := wpItem{}
+			if err := d.DecodeElement(&item, &se); err != nil {
+				return nil, nil, fileError(path, "", err)
+			}
+			if !types[item.PostType] || item.Status == "trash" || item.Status == "auto-draft" {
+				continue
+			}
+			p, err := item.post(o.ctx)
+			if canceled(o.ctx, err) {
+				return out, postErrors, err
+			} else if err != nil {
+				if err := o.postFailed(&postErrors, path, item.PostName, err); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			if p == nil {
+				continue
+			}
+			if lang != "" {
+				// each post gets its own copy, the pointer being shared otherwise
+				l := lang
+				p.Language = &l
+			}
+
+			key := DraftsKey
+			if item.Status == "publish" && item.Password == "" {
+				key = item.Creator
+				if key == "" {
+					key = WordPressKey
+				}
+				key = o.collectionKey(key)
+				p.Collection = key
+			}
+			out[key] = append(out[key], p)
+		}
+	}
+	return out, postErrors, nil
+}
+
+// post converts the item into a post, or returns nil if it has no content.
+func (item wpItem) post(ctx context.Context) (*writeas.PostParams, error) {
+	content := item.Content
+	if !wpBlockTagRx.MatchString(content) {
+		content = wpAutoP(content)
+	}
+	body, err := htmlStringToMarkdown(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	if body == "" {
+		return nil, nil
+	}
+
+	p := &writeas.PostParams{
+		Title:   strings.TrimSpace(item.Title),
+		Slug:    item.PostName,
+		Content: body,
+		Created: wpTime(item.PostDateGMT, item.PostDate),
+		Updated: wpTime(item.ModifiedGMT, item.Modified),
+	}
+	return p, nil
+}
+
+// wpTime parses the GMT date if WordPress set one, drafts have it zeroed,
+// falling back to the local date.
+func wpTime(gmt, local string) *time.Time {
+	if gmt != "" && gmt != wpZeroDate {
+		if t, err := time.Parse(wpDateLayout, gmt); err == nil {
+			return &t
+		}
+	}
+	if local != "" && local != wpZeroDate {
+		if t, err := time.Parse(wpDateLayout, local); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// wpLanguage returns the language code from a locale such as en-US.
+func wpLanguage(locale string) string {
+	locale = strings.TrimSpace(locale)
+	if i := strings.IndexAny(locale, "-_"); i != -1 {
+		locale = locale[:i]
+	}
+	return strings.ToLower(locale)
+}
+
+// wpAutoP wraps blank line separated text in paragraphs and turns the
+// remaining line breaks into br elements, as WordPress does when displaying
+// posts from the classic editor.
+func wpAutoP(content string) string {
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	var b strings.Builder
+	for _, para := range wpParagraphRx.Split(content, -1) {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.Replace(para, "\n", "<br>\n", -1))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const wxr = `<?xml version="1.0" encoding="UTF-8" ?>
+<rss version="2.0"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<title>My Blog</title>
+	<language>en-US</language>
+	<item>
+		<title>Block Post</title>
+		<dc:creator><![CDATA[admin]]></dc:creator>
+		<content:encoded><![CDATA[<!-- wp:paragraph -->
+<p>Hello <strong>world</strong>.</p>
+<!-- /wp:paragraph -->]]></content:encoded>
+		<wp:post_date_gmt>2019-03-10 12:30:00</wp:post_date_gmt>
+		<wp:post_modified_gmt>2019-03-11 12:30:00</wp:post_modified_gmt>
+		<wp:post_name>block-post</wp:post_name>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+	</item>
+	<item>
+		<title>Classic Draft</title>
+		<dc:creator><![CDATA[admin]]></dc:creator>
+		<content:encoded><![CDATA[First paragraph
+still first.
+
+Second paragraph.]]></content:encoded>
+		<wp:post_date>2019-04-10 08:00:00</wp:post_date>
+		<wp:post_date_gmt>0000-00-00 00:00:00</wp:post_date_gmt>
+		<wp:status>draft</wp:status>
+		<wp:post_type>post</wp:post_type>
+	</item>
+	<item>
+		<title>About</title>
+		<content:encoded><![CDATA[<p>A page.</p>]]></content:encoded>
+		<wp:status>publish</wp:status>
+		<wp:post_type>page</wp:post_type>
+	</item>
+	<item>
+		<title>image.png</title>
+		<wp:post_type>attachment</wp:post_type>
+	</item>
+</channel>
+</rss>`
+
+func TestFromWXR(t *testing.T) {
+	colls, _, err := fromWXR(strings.NewReader(wxr), "", []string{"post"}, newOptions(nil))
+	if err != nil {
+		t.Fatalf("failed to parse wxr: %v", err)
+	}
+	if len(colls["admin"]) != 1 {
+		t.Fatalf("post count mismatch: got %d, expecting 1", len(colls["admin"]))
+	}
+	p := colls["admin"][0]
+	if p.Slug != "block-post" || p.Content != "Hello **world**." {
+		t.Fatalf("got slug %q and content %q", p.Slug, p.Content)
+	}
+	if p.Language == nil || *p.Language != "en" {
+		t.Fatalf("got language %v but expected en", p.Language)
+	}
+	if p.Created == nil || p.Updated == nil {
+		t.Fatal("expected created and updated dates to be set")
+	}
+
+	if len(colls[DraftsKey]) != 1 {
+		t.Fatalf("draft count mismatch: got %d, expecting 1", len(colls[DraftsKey]))
+	}
+	draft := colls[DraftsKey][0]
+	*p.Language = "fr"
+	if draft.Language == nil || *draft.Language != "en" {
+		t.Fatalf("got draft language %v but expected en", draft.Language)
+	}
+	if draft.Content != "First paragraph  \nstill first.\n\nSecond paragraph." {
+		t.Fatalf("got draft content %q", draft.Content)
+	}
+	if draft.Created == nil || draft.Created.Hour() != 8 {
+		t.Fatalf("got draft created %v but expected the local post date", draft.Created)
+	}
+
+	colls, _, err = fromWXR(strings.NewReader(wxr), "", []string{"post", "page"}, newOptions(nil))
+	if err != nil {
+		t.Fatalf("failed to parse wxr: %v", err)
+	}
+	if len(colls[WordPressKey]) != 1 {
+		t.Fatalf("page count mismatch: got %d, expecting 1", len(colls[WordPressKey]))
+	}
+}
+
+func TestFromWXRContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	colls, _, err := fromWXR(strings.NewReader(wxr), "", []string{"post"}, contextOptions(ctx, nil))
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls[DraftsKey]) != 0 {
+		t.Fatalf("draft count mismatch: got %d, expecting 0", len(colls[DraftsKey]))
+	}
+}
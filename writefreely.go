@@ -0,0 +1,139 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/writeas/go-writeas/v2"
+)
+
+// wfExport is a user export from WriteFreely or Write.as in JSON.
+type wfExport struct {
+	Username    string            `json:"username"`
+	Collections []wfCollection    `json:"collections"`
+	Posts       []json.RawMessage `json:"posts"`
+}
+
+// wfCollection is a collection of an export. Its posts, as those of the
+// export, are decoded one at a time so a malformed post can be skipped.
+type wfCollection struct {
+	Alias string            `json:"alias"`
+	Posts []json.RawMessage `json:"posts"`
+}
+
+// wfPost is a post as exported by WriteFreely. Both the API names for the
+// font and language and those used by writeas.PostParams are accepted.
+type wfPost struct {
+	ID         string     `json:"id"`
+	Slug       string     `json:"slug"`
+	Appearance string     `json:"appearance"`
+	Font       string     `json:"font"`
+	Language   *string    `json:"language"`
+	Lang       *string    `json:"lang"`
+	RTL        *bool      `json:"rtl"`
+	Created    *time.Time `json:"created"`
+	Updated    *time.Time `json:"updated"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+}
+
+// FromWriteFreelyJSON reads a WriteFreely or Write.as JSON export from r and
+// returns the posts it contains and an error if any.
+//
+// Posts are keyed by the alias of the collection they belong to and posts
+// without a collection are included under DraftsKey. The post ID, slug,
+// dates, title, font, language and direction are kept as they were exported
+// so posts can be moved between instances without changes. Posts that fail
+// to be decoded are handled according to the ErrorPolicy.
+func FromWriteFreelyJSON(r io.Reader, opts ...Option) (ZipCollections, error) {
+	return fromWriteFreelyJSON(r, newOptions(opts))
+}
+
+// FromWriteFreelyJSONContext works as FromWriteFreelyJSON, checking ctx
+// between posts. Once ctx is done the posts read until then are returned
+// along with ctx.Err().
+func FromWriteFreelyJSONContext(ctx context.Context, r io.Reader, opts ...Option) (ZipCollections, error) {
+	return fromWriteFreelyJSON(r, contextOptions(ctx, opts))
+}
+
+func fromWriteFreelyJSON(r io.Reader, o *options) (ZipCollections, error) {
+	export := wfExport{}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+
+	var postErrors error
+	out := ZipCollections{DraftsKey: []*writeas.PostParams{}}
+	add := func(alias string, posts []json.RawMessage) error {
+		key, coll := DraftsKey, ""
+		if alias != "" {
+			key = o.collectionKey(alias)
+			coll = key
+		}
+		if out[key] == nil {
+			out[key] = []*writeas.PostParams{}
+		}
+		for i, raw := range posts {
+			if err := o.ctx.Err(); err != nil {
+				return err
+			}
+			wp := wfPost{}
+			if err := json.Unmarshal(raw, &wp); err != nil {
+				id := strconv.Itoa(i + 1)
+				if alias != "" {
+					id = alias + "/" + id
+				}
+				if err := o.postFailed(&postErrors, "", id, err); err != nil {
+					return err
+				}
+				continue
+			}
+			out[key] = append(out[key], wp.post(coll))
+		}
+		return nil
+	}
+	err := add("", export.Posts)
+	for _, c := range export.Collections {
+		if err != nil {
+			break
+		}
+		err = add(c.Alias, c.Posts)
+	}
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return out, err
+}
+
+func (wp wfPost) post(collection string) *writeas.PostParams {
+	p := &writeas.PostParams{
+		ID:         wp.ID,
+		Slug:       wp.Slug,
+		Created:    wp.Created,
+		Updated:    wp.Updated,
+		Title:      wp.Title,
+		Content:    wp.Body,
+		Font:       wp.Appearance,
+		IsRTL:      wp.RTL,
+		Language:   wp.Language,
+		Collection: collection,
+	}
+	if p.Font == "" {
+		p.Font = wp.Font
+	}
+	if p.Language == nil {
+		p.Language = wp.Lang
+	}
+	return p
+}
@@ -0,0 +1,133 @@
+// Copyright © 2019-2020 A Bunch Tell LLC. and contributors.
+//
+// This is free software: you can redistribute it and/or modify
+// it under the terms of the Mozilla Public License, included
+// in the LICENSE file in this source code package.
+
+package wfimport
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const wfExportJSON = `{
+	"username": "matt",
+	"collections": [{
+		"alias": "blog",
+		"title": "My Blog",
+		"posts": [{
+			"id": "4lxa5ryjqd1b9xeh",
+			"slug": "hello-world",
+			"appearance": "serif",
+			"language": "en",
+			"rtl": false,
+			"created": "2019-03-10T12:30:00Z",
+			"updated": "2019-03-11T12:30:00Z",
+			"title": "Hello World",
+			"body": "The first post."
+		}]
+	}, {
+		"alias": "empty",
+		"posts": []
+	}],
+	"posts": [{
+		"id": "ewd1c4tp2ydnqo2c",
+		"slug": null,
+		"appearance": "mono",
+		"language": "",
+		"rtl": null,
+		"created": "2019-04-10T08:00:00Z",
+		"updated": "2019-04-10T08:00:00Z",
+		"title": "",
+		"body": "An anonymous post."
+	}]
+}`
+
+func TestFromWriteFreelyJSON(t *testing.T) {
+	colls, err := FromWriteFreelyJSON(strings.NewReader(wfExportJSON))
+	if err != nil {
+		t.Fatalf("failed to parse export: %v", err)
+	}
+	if len(colls) != 3 {
+		t.Fatalf("collection count mismatch: got %d, expecting 3", len(colls))
+	}
+	if colls["empty"] == nil {
+		t.Fatal("empty collection should not be nil")
+	}
+	if len(colls[DraftsKey]) != 1 {
+		t.Fatalf("draft count mismatch: got %d, expecting 1", len(colls[DraftsKey]))
+	}
+	if d := colls[DraftsKey][0]; d.ID != "ewd1c4tp2ydnqo2c" || d.Font != "mono" || d.IsRTL != nil {
+		t.Fatalf("draft fields were not kept: %+v", d)
+	}
+
+	if len(colls["blog"]) != 1 {
+		t.Fatalf("post count mismatch: got %d, expecting 1", len(colls["blog"]))
+	}
+	p := colls["blog"][0]
+	if p.ID != "4lxa5ryjqd1b9xeh" || p.Slug != "hello-world" || p.Font != "serif" {
+		t.Fatalf("post fields were not kept: %+v", p)
+	}
+	if p.Language == nil || *p.Language != "en" {
+		t.Fatalf("got language %v but expected en", p.Language)
+	}
+	if p.IsRTL == nil || *p.IsRTL {
+		t.Fatalf("got rtl %v but expected false", p.IsRTL)
+	}
+	if p.Created == nil || p.Created.Format("2006-01-02T15:04:05Z07:00") != "2019-03-10T12:30:00Z" {
+		t.Fatalf("got created %v", p.Created)
+	}
+	if p.Collection != "blog" {
+		t.Fatalf("got collection %q but expected blog", p.Collection)
+	}
+}
+
+func TestFromWriteFreelyJSONErrors(t *testing.T) {
+	export := `{"collections": [{"alias": "blog", "posts": [
+		{"id": "a", "body": "fine"},
+		{"id": "b", "body": "bad date", "created": "yesterday"}
+	]}]}`
+
+	colls, err := FromWriteFreelyJSON(strings.NewReader(export))
+	merr, ok := err.(*multierror.Error)
+	if !ok || len(merr.Errors) != 1 {
+		t.Fatalf("got error %v but expected one collected error", err)
+	}
+	if !strings.HasPrefix(merr.Errors[0].Error(), "post blog/2: ") {
+		t.Fatalf("got error %q but expected it to name the post", merr.Errors[0])
+	}
+	if len(colls["blog"]) != 1 || colls["blog"][0].ID != "a" {
+		t.Fatalf("got posts %v but expected the valid one", colls["blog"])
+	}
+
+	colls, err = FromWriteFreelyJSON(strings.NewReader(export), WithErrorPolicy(FailFast))
+	if err == nil || colls != nil {
+		t.Fatalf("got %v and error %v but expected to fail", colls, err)
+	}
+
+	colls, err = FromWriteFreelyJSON(strings.NewReader(export), WithErrorPolicy(SkipSilently))
+	if err != nil || len(colls["blog"]) != 1 {
+		t.Fatalf("got %v and error %v but expected the valid post alone", colls, err)
+	}
+}
+
+func TestFromWriteFreelyJSONContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	colls, err := FromWriteFreelyJSONContext(ctx, strings.NewReader(wfExportJSON))
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(colls) != 1 || len(colls[DraftsKey]) != 0 {
+		t.Fatalf("got %v but expected no posts", colls)
+	}
+
+	_, err = FromWriteFreelyJSON(strings.NewReader(wfExportJSON), WithContext(ctx))
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+}
@@ -8,7 +8,7 @@ package wfimport
 
 import (
 	"archive/zip"
-	"path/filepath"
+	"context"
 
 	"github.com/writeas/go-writeas/v2"
 )
@@ -30,100 +30,160 @@ type ZipCollections map[string][]*writeas.PostParams
 // For an example, see the file zip_funcs.go
 type ZipFunc func(f *zip.File) (*writeas.PostParams, error)
 
+// ZipContextFunc is a ZipFunc that is passed the context of the import, so
+// that long conversions can stop once it is done by returning ctx.Err().
+type ZipContextFunc func(ctx context.Context, f *zip.File) (*writeas.PostParams, error)
+
+// zipParseFunc is a ZipContextFunc that also returns what was learned of the
+// file while parsing it, which only the package's own functions know.
+type zipParseFunc func(ctx context.Context, f *zip.File) (*writeas.PostParams, parseInfo, error)
+
+// withContext returns f as a ZipContextFunc ignoring the context, which is
+// still checked between files.
+func (f ZipFunc) withContext() ZipContextFunc {
+	return func(_ context.Context, zf *zip.File) (*writeas.PostParams, error) {
+		return f(zf)
+	}
+}
+
+// parser returns f as a zipParseFunc, nothing being known of the files it
+// parses.
+func (f ZipContextFunc) parser() zipParseFunc {
+	return func(ctx context.Context, zf *zip.File) (*writeas.PostParams, parseInfo, error) {
+		p, err := f(ctx, zf)
+		return p, parseInfo{}, err
+	}
+}
+
 // FromZip opens a zip archive and returns a slice of *writeas.PostParams
 // and an error if any. It only reads the top level of the archive tree.
-func FromZip(archive string) ([]*writeas.PostParams, error) {
-	return FromZipByFunc(archive, TopLevelZipFunc)
+func FromZip(archive string, opts ...Option) ([]*writeas.PostParams, error) {
+	return postsFromZip(archive, topLevelZipFile, newOptions(opts))
 }
 
 // FromZipByFunc opens an archive and filters the contents according to the
 // passed ZipFunc. It returns a slice of writeas.PostParams and any error.
-func FromZipByFunc(archive string, f ZipFunc) ([]*writeas.PostParams, error) {
-	a, err := zip.OpenReader(archive)
-	if err != nil {
-		return nil, err
-	}
-	defer a.Close()
+func FromZipByFunc(archive string, f ZipFunc, opts ...Option) ([]*writeas.PostParams, error) {
+	return postsFromZip(archive, f.withContext().parser(), newOptions(opts))
+}
+
+// FromZipContext works as FromZip, stopping once ctx is done. The posts read
+// until then are returned along with ctx.Err().
+func FromZipContext(ctx context.Context, archive string, opts ...Option) ([]*writeas.PostParams, error) {
+	return postsFromZip(archive, topLevelZipFile, contextOptions(ctx, opts))
+}
 
-	return postsFromZipFiles(a.File, f)
+// FromZipByFuncContext works as FromZipByFunc, passing ctx to f and stopping
+// once ctx is done. The posts read until then are returned along with
+// ctx.Err().
+func FromZipByFuncContext(ctx context.Context, archive string, f ZipContextFunc, opts ...Option) ([]*writeas.PostParams, error) {
+	return postsFromZip(archive, f.parser(), contextOptions(ctx, opts))
 }
 
 // FromZipDirs opens a zip archive and returns a map of post collections
 // and an error if any.
 //
 // The map is of [string][]*writeas.PostParams where the string key is the name
-// of the directory. The top level directory posts will be 'drafts'.
-func FromZipDirs(archive string) (ZipCollections, error) {
-	return FromZipDirsByFunc(archive, TopLevelZipFunc)
+// of the first level directory, as for FromFS. The top level directory posts
+// will be 'drafts'.
+func FromZipDirs(archive string, opts ...Option) (ZipCollections, error) {
+	return postsFromZipDirs(archive, topLevelZipFile, newOptions(opts))
 }
 
 // FromZipDirsByFunc works as FromZipDirs but filtering files through f.
-func FromZipDirsByFunc(archive string, f ZipFunc) (ZipCollections, error) {
-	return postsFromZipDirs(archive, f)
+func FromZipDirsByFunc(archive string, f ZipFunc, opts ...Option) (ZipCollections, error) {
+	return postsFromZipDirs(archive, f.withContext().parser(), newOptions(opts))
 }
 
-func postsFromZipFiles(files []*zip.File, f ZipFunc) ([]*writeas.PostParams, error) {
-	posts := []*writeas.PostParams{}
-	for _, file := range files {
-		post, err := f(file)
-		if err == ErrEmptyFile || err == ErrInvalidContentType {
-			continue
-		} else if err != nil {
-			return nil, err
-		}
-		if post != nil {
-			posts = append(posts, post)
-		}
+// FromZipDirsContext works as FromZipDirs, stopping once ctx is done. The
+// collections read until then are returned along with ctx.Err().
+func FromZipDirsContext(ctx context.Context, archive string, opts ...Option) (ZipCollections, error) {
+	return postsFromZipDirs(archive, topLevelZipFile, contextOptions(ctx, opts))
+}
+
+// FromZipDirsByFuncContext works as FromZipDirsByFunc, passing ctx to f and
+// stopping once ctx is done. The collections read until then are returned
+// along with ctx.Err().
+func FromZipDirsByFuncContext(ctx context.Context, archive string, f ZipContextFunc, opts ...Option) (ZipCollections, error) {
+	return postsFromZipDirs(archive, f.parser(), contextOptions(ctx, opts))
+}
+
+// WalkZip opens a zip archive and calls fn for every post parsed from it by
+// f, in the order they are stored, without keeping them in memory. The
+// collection passed to fn is the one the post would have in FromZipDirsByFunc.
+func WalkZip(archive string, f ZipFunc, fn WalkFunc, opts ...Option) error {
+	a, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
 	}
-	if len(posts) > 0 {
-		return posts, nil
+	defer a.Close()
+
+	postErrors, err := walkZipFiles(archive, a.File, f.withContext().parser(), visitPosts(fn), newOptions(opts))
+	if err != nil {
+		return err
 	}
-	return nil, nil
+	return postErrors
 }
 
-func postsFromZipDirs(archive string, f ZipFunc) (ZipCollections, error) {
-	out := make(ZipCollections)
+// walkZipFiles visits the posts parsed from files by f. Errors parsing single
+// files are gathered into postErrors, as the error policy allows, while err
+// is set if the walk was aborted.
+func walkZipFiles(archive string, files []*zip.File, f zipParseFunc, visit visitFunc, o *options) (postErrors, err error) {
+	if err := visit(DraftsKey, nil); err != nil {
+		return nil, walkResult(err)
+	}
+	infos := make([]parseInfo, len(files))
+	err = parseOrdered(o.ctx, o.workers, len(files), func(i int) (post *writeas.PostParams, err error) {
+		post, infos[i], err = f(o.ctx, files[i])
+		return post, err
+	}, func(i int, post *writeas.PostParams, err error) error {
+		return o.visitArchiveEntry(visit, archive, files[i].Name, infos[i], post, err, &postErrors)
+	})
+	return postErrors, walkResult(err)
+}
+
+func postsFromZip(archive string, f zipParseFunc, o *options) ([]*writeas.PostParams, error) {
 	a, err := zip.OpenReader(archive)
 	if err != nil {
 		return nil, err
 	}
 	defer a.Close()
 
-	drafts := []*writeas.PostParams{}
-	dirs := make(map[string][]*zip.File)
-	for _, file := range a.File {
-		dir, _ := filepath.Split(file.Name)
-		if dir != "" {
-			dir = filepath.Dir(dir)
-			if dirs[dir] == nil {
-				dirs[dir] = []*zip.File{}
-			}
-			dirs[dir] = append(dirs[dir], file)
-		} else {
-			post, err := f(file)
-			if err == ErrEmptyFile || err == ErrInvalidContentType {
-				continue
-			} else if err != nil {
-				return nil, err
-			}
-			drafts = append(drafts, post)
+	return postsFromZipFiles(archive, a.File, f, o)
+}
+
+func postsFromZipFiles(archive string, files []*zip.File, f zipParseFunc, o *options) ([]*writeas.PostParams, error) {
+	posts := []*writeas.PostParams{}
+	postErrors, err := walkZipFiles(archive, files, f, func(coll string, p *writeas.PostParams) error {
+		if p != nil {
+			posts = append(posts, p)
 		}
+		return nil
+	}, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
 	}
-	out[DraftsKey] = drafts
-	for dirName, dirList := range dirs {
-		if out[dirName] == nil {
-			out[dirName] = []*writeas.PostParams{}
-		}
-		for _, file := range dirList {
-			post, err := f(file)
-			if err == ErrEmptyFile || err == ErrInvalidContentType {
-				continue
-			} else if err != nil {
-				return nil, err
-			}
-			out[dirName] = append(out[dirName], post)
-		}
+	if len(posts) > 0 {
+		return posts, err
+	}
+	return nil, err
+}
+
+func postsFromZipDirs(archive string, f zipParseFunc, o *options) (ZipCollections, error) {
+	a, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
 	}
+	defer a.Close()
 
-	return out, nil
+	out := make(ZipCollections)
+	postErrors, err := walkZipFiles(archive, a.File, f, out.add, o)
+	if err != nil && !canceled(o.ctx, err) {
+		return nil, err
+	} else if err == nil {
+		err = postErrors
+	}
+	return out, err
 }
@@ -8,9 +8,12 @@ package wfimport
 
 import (
 	"archive/zip"
-	"bufio"
+	"context"
+	"io/ioutil"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/writeas/go-writeas/v2"
 )
@@ -21,55 +24,110 @@ import (
 // This is an example of a ZipFunc that can be used to filter files parse from
 // a zip archive.
 func TopLevelZipFunc(f *zip.File) (*writeas.PostParams, error) {
+	return TopLevelZipContextFunc(context.Background(), f)
+}
+
+// TopLevelZipContextFunc is the ZipContextFunc form of TopLevelZipFunc.
+func TopLevelZipContextFunc(ctx context.Context, f *zip.File) (*writeas.PostParams, error) {
+	p, _, err := topLevelZipFile(ctx, f)
+	return p, err
+}
+
+// topLevelZipFile works as TopLevelZipContextFunc, also returning what was
+// learned of the file while parsing it.
+func topLevelZipFile(_ context.Context, f *zip.File) (*writeas.PostParams, parseInfo, error) {
 	if !f.FileInfo().IsDir() {
 		return openAndParse(f)
 	}
-	return nil, nil
+	return nil, parseInfo{}, nil
 }
 
 // TextFileZipFunc parses .txt files into PostParams
 func TextFileZipFunc(f *zip.File) (*writeas.PostParams, error) {
+	return TextFileZipContextFunc(context.Background(), f)
+}
+
+// TextFileZipContextFunc is the ZipContextFunc form of TextFileZipFunc.
+func TextFileZipContextFunc(ctx context.Context, f *zip.File) (*writeas.PostParams, error) {
 	if !f.FileInfo().IsDir() && filepath.Ext(f.FileHeader.Name) == ".txt" {
-		return openAndParse(f)
+		p, _, err := openAndParse(f)
+		return p, err
 	}
 	return nil, nil
 }
 
-func openAndParse(f *zip.File) (*writeas.PostParams, error) {
-	rc, err := f.Open()
+func openAndParse(f *zip.File) (*writeas.PostParams, parseInfo, error) {
+	b, err := readZipFile(f)
 	if err != nil {
-		return nil, err
+		return nil, parseInfo{}, err
 	}
-	defer rc.Close()
+	return parseArchiveFile(f.FileHeader.Name, b, f.Modified)
+}
 
-	r := bufio.NewReader(rc)
-	b := make([]byte, f.FileInfo().Size())
-	_, err = r.Read(b)
+// parseInfo is what the package's own parsing of an archive entry learns
+// of it besides the post.
+type parseInfo struct {
+	// enc is the encoding the entry was read in, empty if not known.
+	enc string
+	// draft is set if the front matter of the entry marked it as a draft.
+	draft bool
+}
+
+// parseArchiveFile parses the contents b of the archive entry name into a
+// post, taking the ID, slug and collection from the entry name as FromFS
+// does.
+func parseArchiveFile(name string, b []byte, modTime time.Time) (*writeas.PostParams, parseInfo, error) {
+	p, fm, enc, err := parsePost(name, b, modTime)
 	if err != nil {
-		return nil, err
+		return nil, parseInfo{enc: enc}, err
 	}
-	p, err := fromBytes(b)
+	applyPathParts(name, p, fm)
+	return p, parseInfo{enc: enc, draft: fm != nil && fm.Draft}, nil
+}
+
+// readZipFile returns the full uncompressed contents of f.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
 	if err != nil {
 		return nil, err
 	}
-	p.Created = &f.Modified
+	defer rc.Close()
 
-	p.ID, p.Slug, p.Collection = filenameParts(f.FileHeader.Name)
-	return p, nil
+	return ioutil.ReadAll(rc)
 }
 
+// filenameParts splits the slash separated path of a file, relative to the
+// root of an import, into the ID and slug in its name, as in
+// my-post_839ruu389ru9.txt, and the first level directory it is in.
 func filenameParts(filename string) (id, slug, coll string) {
-	filename = strings.TrimSuffix(filename, ".txt")
 	seg := strings.Split(filename, "/")
 	if len(seg) > 1 {
 		coll = seg[0]
-		filename = seg[1]
 	}
-	seg = strings.Split(filename, "_")
-	if len(seg) > 1 {
-		slug = seg[0]
-		filename = seg[1]
+	filename = seg[len(seg)-1]
+	filename = strings.TrimSuffix(filename, path.Ext(filename))
+	if i := strings.LastIndex(filename, "_"); i != -1 {
+		slug = filename[:i]
+		filename = filename[i+1:]
 	}
 	id = filename
 	return
 }
+
+// applyPathParts sets the ID of p from the path rel of its file, relative to
+// the root of an import, and its slug and collection if the front matter fm
+// did not set them, as given by filenameParts. It returns the key of p in a
+// ZipCollections map, DraftsKey for files at the top level or marked as
+// drafts.
+func applyPathParts(rel string, p *writeas.PostParams, fm *frontMatter) string {
+	id, slug, coll := filenameParts(rel)
+	p.ID = id
+	if p.Slug == "" {
+		p.Slug = slug
+	}
+	if coll == "" || fm != nil && fm.Draft {
+		return DraftsKey
+	}
+	p.Collection = coll
+	return coll
+}
@@ -9,9 +9,12 @@ package wfimport
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/writeas/go-writeas/v2"
 )
 
 type fileList []struct {
@@ -74,6 +77,20 @@ var filenames = []struct {
 		Slug:       "",
 		ID:         "839ruu389ru9",
 	},
+	{
+		Name:       "nested directory",
+		Filename:   "rob/2019/ubuntu-next_839ruu389ru9.md",
+		Collection: "rob",
+		Slug:       "ubuntu-next",
+		ID:         "839ruu389ru9",
+	},
+	{
+		Name:       "underscore in slug",
+		Filename:   "ubuntu_next_839ruu389ru9.txt",
+		Collection: "",
+		Slug:       "ubuntu_next",
+		ID:         "839ruu389ru9",
+	},
 }
 
 func TestFromZip(t *testing.T) {
@@ -122,6 +139,44 @@ func TestFromZipDirs(t *testing.T) {
 	}
 }
 
+func TestFromZipContext(t *testing.T) {
+	a := getTestZip(t, files)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	posts, err := FromZipContext(ctx, a)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if posts != nil {
+		t.Fatalf("got %d posts but expected none", len(posts))
+	}
+
+	// cancel while parsing, the posts read until then are returned
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	posts, err = FromZipByFuncContext(ctx, a, func(ctx context.Context, f *zip.File) (*writeas.PostParams, error) {
+		p, err := TopLevelZipFunc(f)
+		cancel()
+		return p, err
+	})
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("Post count mismatch: got %d but expected %d", len(posts), 1)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	postMap, err := FromZipDirsContext(ctx, a)
+	if err != context.Canceled {
+		t.Fatalf("got error %v but expected %v", err, context.Canceled)
+	}
+	if postMap == nil || len(postMap[DraftsKey]) != 0 {
+		t.Fatalf("got %v but expected an empty drafts collection", postMap)
+	}
+}
+
 func getTestZip(t *testing.T, files fileList) string {
 	buf := new(bytes.Buffer)
 	w := zip.NewWriter(buf)